@@ -4,8 +4,8 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"os"
-	"os/user"
 	"path/filepath"
+	"sync"
 
 	"github.com/lbryio/lbry.go/v2/extras/errors"
 	"github.com/lbryio/reflector.go/cmd"
@@ -19,12 +19,17 @@ import (
 
 var dbHandle *db.SQL
 
-func ReflectAndClean() error {
-	err := reflectBlobs()
+// reflectMux serializes the reflection of the blobs of the daemons that run concurrently: they share the database
+// connection and the log level is lowered while uploading
+var reflectMux sync.Mutex
+
+// ReflectAndClean uploads the blobs of the daemon, which must be stopped, then removes its database and its blobs
+func ReflectAndClean(instance util.Instance) error {
+	err := reflectBlobs(instance)
 	if err != nil {
 		return err
 	}
-	return util.CleanupLbrynet()
+	return instance.Cleanup()
 }
 
 func loadConfig(path string) (cmd.Config, error) {
@@ -42,18 +47,20 @@ func loadConfig(path string) (cmd.Config, error) {
 	return c, err
 }
 
-func reflectBlobs() error {
+func reflectBlobs(instance util.Instance) error {
 	if util.IsBlobReflectionOff() {
 		return nil
 	}
 	//make sure lbrynet is off
-	running, err := util.IsLbrynetRunning()
+	running, err := instance.IsRunning()
 	if err != nil {
 		return err
 	}
 	if running {
 		return errors.Prefix("cannot reflect blobs as the daemon is running", err)
 	}
+	reflectMux.Lock()
+	defer reflectMux.Unlock()
 	logrus.SetLevel(logrus.InfoLevel)
 	defer logrus.SetLevel(logrus.DebugLevel)
 	ex, err := os.Executable()
@@ -78,12 +85,7 @@ func reflectBlobs() error {
 
 	uploadWorkers := 10
 	uploader := reflector.NewUploader(dbHandle, st, uploadWorkers, false)
-	usr, err := user.Current()
-	if err != nil {
-		return errors.Err(err)
-	}
-	blobsDir := usr.HomeDir + "/.lbrynet/blobfiles/"
-	err = uploader.Upload(blobsDir)
+	err = uploader.Upload(instance.GetBlobsDir())
 	if err != nil {
 		return errors.Err(err)
 	}
@@ -0,0 +1,91 @@
+package downloader
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lbryio/ytsync/errclass"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+)
+
+const (
+	YoutubeDL = "youtube-dl"
+	YtDlp     = "yt-dlp"
+)
+
+var Backends = []string{YoutubeDL, YtDlp}
+
+// DefaultQualities is the format ladder used when a channel doesn't specify one: the best format under the first
+// height is attempted first, lower ones are only used if the download of the higher ones fails
+var DefaultQualities = []string{"1080", "720", "480", "320"}
+
+var ErrThrottled = errclass.New(errclass.Retryable, "HTTP Error 429: the source is throttling this IP")
+var ErrGeoBlocked = errclass.New(errclass.NoRetry, "uploader has not made this video available in your country")
+var ErrTooLong = errclass.New(errclass.PermanentVideo, "video is too long to process")
+var ErrTooBig = errclass.New(errclass.PermanentVideo, "the video is too big to sync, skipping for now")
+var ErrAgeRestricted = errclass.New(errclass.PermanentVideo, "Sign in to confirm your age")
+var ErrUnavailableFragments = errclass.New(errclass.PermanentVideo, "giving up after 0 fragment retries")
+var ErrInterrupted = errclass.New(errclass.Interrupted, "interrupted by user")
+
+// Progress is a snapshot of the state of a running download
+type Progress struct {
+	DownloadedBytes int64
+	TotalBytes      int64
+	// Speed is expressed in bytes per second
+	Speed float64
+	ETA   time.Duration
+}
+
+func (p Progress) String() string {
+	percent := 0.0
+	if p.TotalBytes > 0 {
+		percent = float64(p.DownloadedBytes) / float64(p.TotalBytes) * 100
+	}
+	return fmt.Sprintf("%.1f%% of %.2fMiB at %.2fMiB/s ETA %s", percent, float64(p.TotalBytes)/1024/1024, p.Speed/1024/1024, p.ETA)
+}
+
+// SubtitleOptions describe which captions are downloaded and embedded in the video as text tracks
+type SubtitleOptions struct {
+	// Languages restricts the captions to the given languages, all the available ones are downloaded when empty
+	Languages []string
+	// Auto enables automatically generated captions
+	Auto bool
+}
+
+// Options describe what has to be downloaded and how
+type Options struct {
+	URL string
+	// OutputPath is the path of the resulting mp4 file
+	OutputPath    string
+	SourceAddress string
+	// MaxFileSize is expressed in MB, 0 disables the check
+	MaxFileSize int
+	// MaxDuration is expressed in seconds, 0 disables the check
+	MaxDuration int
+	Qualities   []string
+	// Subtitles enables captions, nil disables them
+	Subtitles *SubtitleOptions
+	// WriteInfo saves the metadata extracted by the download tool next to the output, see ReadChapters
+	WriteInfo bool
+	// OnProgress is called every time the download tool reports progress
+	OnProgress func(Progress)
+	// Stop interrupts the download when closed
+	Stop <-chan struct{}
+}
+
+// Downloader fetches media from remote platforms
+type Downloader interface {
+	Download(options Options) error
+}
+
+// New returns the downloader backed by the given tool
+func New(backend string) (Downloader, error) {
+	switch backend {
+	case YoutubeDL:
+		return &ytdl{binary: YoutubeDL}, nil
+	case YtDlp:
+		return &ytdl{binary: YtDlp, progressTemplate: true}, nil
+	}
+	return nil, errors.Err("unknown downloader %s. Supported: %v", backend, Backends)
+}
@@ -0,0 +1,56 @@
+package downloader
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+
+	"github.com/lbryio/ytsync/media"
+)
+
+// info is the subset of the metadata written by the download tool that ytsync uses
+type info struct {
+	Chapters []struct {
+		StartTime float64 `json:"start_time"`
+		EndTime   float64 `json:"end_time"`
+		Title     string  `json:"title"`
+	} `json:"chapters"`
+}
+
+func infoPath(outputPath string) string {
+	return strings.TrimSuffix(outputPath, ".mp4") + ".info.json"
+}
+
+// ReadChapters returns the chapters found by the download tool for the video downloaded to outputPath with WriteInfo
+// enabled. The metadata file is removed once it's read. No chapters are returned if the file doesn't exist.
+func ReadChapters(outputPath string) ([]media.Chapter, error) {
+	path := infoPath(outputPath)
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Err(err)
+	}
+	_ = os.Remove(path)
+	return parseChapters(data)
+}
+
+func parseChapters(data []byte) ([]media.Chapter, error) {
+	var i info
+	err := json.Unmarshal(data, &i)
+	if err != nil {
+		return nil, errors.Prefix("invalid info file", errors.Err(err))
+	}
+	chapters := make([]media.Chapter, 0, len(i.Chapters))
+	for _, c := range i.Chapters {
+		chapters = append(chapters, media.Chapter{
+			Start: c.StartTime,
+			End:   c.EndTime,
+			Title: c.Title,
+		})
+	}
+	return chapters, nil
+}
@@ -0,0 +1,358 @@
+package downloader
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const progressPrefix = "ytsync-progress:"
+
+// maxOutputLine is the longest line of output expected from the tool, the JSON it prints can be long
+const maxOutputLine = 4 * 1024 * 1024
+
+// ytdl drives youtube-dl and its fork yt-dlp, which share the same command line interface
+type ytdl struct {
+	binary string
+	// progressTemplate is true for tools that support --progress-template (yt-dlp) and thus report progress as JSON
+	progressTemplate bool
+}
+
+func (y *ytdl) baseArgs(o Options) []string {
+	args := []string{
+		"--newline",
+		"-o" + strings.TrimSuffix(o.OutputPath, ".mp4"),
+		"--merge-output-format",
+		"mp4",
+		"--postprocessor-args",
+		"-movflags faststart",
+		"--abort-on-unavailable-fragment",
+		"--fragment-retries",
+		"0",
+		"--user-agent",
+		"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/79.0.3945.88 Safari/537.36",
+		"--cookies",
+		"cookies.txt",
+	}
+	if y.progressTemplate {
+		args = append(args, "--progress-template", "download:"+progressPrefix+"%(progress)j")
+	}
+	if o.MaxFileSize > 0 {
+		args = append(args,
+			"--max-filesize",
+			fmt.Sprintf("%dM", o.MaxFileSize),
+		)
+	}
+	if o.MaxDuration > 0 {
+		args = append(args,
+			"--match-filter",
+			fmt.Sprintf("duration <= %d", o.MaxDuration),
+		)
+	}
+	if o.Subtitles != nil {
+		args = append(args,
+			"--write-sub",
+			"--convert-subs",
+			"srt",
+			"--embed-subs",
+		)
+		if o.Subtitles.Auto {
+			args = append(args, "--write-auto-sub")
+		}
+		if len(o.Subtitles.Languages) > 0 {
+			args = append(args, "--sub-lang", strings.Join(o.Subtitles.Languages, ","))
+		} else {
+			args = append(args, "--all-subs")
+		}
+	}
+	if o.WriteInfo {
+		args = append(args, "--write-info-json")
+	}
+	if o.SourceAddress != "" {
+		args = append(args,
+			"--source-address",
+			o.SourceAddress,
+		)
+	}
+	return append(args, o.URL)
+}
+
+func (y *ytdl) Download(o Options) error {
+	qualities := o.Qualities
+	if len(qualities) == 0 {
+		qualities = DefaultQualities
+	}
+	args := y.baseArgs(o)
+	for i, quality := range qualities {
+		argsWithFilters := append(append([]string{}, args...), "-fbestvideo[ext=mp4][height<="+quality+"]+bestaudio[ext!=webm]")
+		err := y.run(argsWithFilters, o)
+		if err != nil {
+			if errors.Is(err, ErrUnavailableFragments) && i < len(qualities)-1 {
+				continue //this bypasses the yt throttling IP redistribution... TODO: don't
+			}
+			return err
+		}
+		return nil
+	}
+	return nil
+}
+
+func (y *ytdl) run(args []string, o Options) error {
+	cmd := exec.Command(y.binary, args...)
+	log.Printf("Running command %s %s", y.binary, strings.Join(args, " "))
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return errors.Err(err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return errors.Err(err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return errors.Err(err)
+	}
+
+	done := make(chan struct{})
+	interrupted := make(chan struct{})
+	go func() {
+		select {
+		case <-o.Stop:
+			close(interrupted)
+			_ = cmd.Process.Kill()
+		case <-done:
+		}
+	}()
+	defer close(done)
+
+	errorLogChan := make(chan []byte, 1)
+	go func() {
+		errorLog, _ := ioutil.ReadAll(stderr)
+		errorLogChan <- errorLog
+	}()
+
+	var outLog strings.Builder
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxOutputLine)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if p, ok := y.parseProgress(line); ok {
+			if o.OnProgress != nil {
+				o.OnProgress(p)
+			}
+			continue
+		}
+		outLog.WriteString(line + "\n")
+	}
+	scanErr := scanner.Err()
+	// the output must be drained whatever happened, the tool would block on a full pipe otherwise
+	_, _ = io.Copy(ioutil.Discard, stdout)
+	errorLog := string(<-errorLogChan)
+	err = cmd.Wait()
+
+	select {
+	case <-interrupted:
+		removeOutput(o.OutputPath)
+		return errors.Err(ErrInterrupted)
+	default:
+	}
+	if err != nil {
+		return classifyError(errorLog, err)
+	}
+	if scanErr != nil {
+		removeOutput(o.OutputPath)
+		return errors.Prefix("could not read the output of "+y.binary, scanErr)
+	}
+	log.Debugln(outLog.String())
+
+	if strings.Contains(outLog.String(), "does not pass filter duration") {
+		removeOutput(o.OutputPath)
+		return errors.Err(ErrTooLong)
+	}
+	if strings.Contains(outLog.String(), "File is larger than max-filesize") {
+		removeOutput(o.OutputPath)
+		return errors.Err(ErrTooBig)
+	}
+	if errorLines := withoutWarnings(errorLog); errorLines != "" {
+		log.Printf("Command finished with error: %s", errorLines)
+		removeOutput(o.OutputPath)
+		return classifyError(errorLines, nil)
+	}
+	return nil
+}
+
+// removeOutput deletes whatever the tool left behind for the given output path, merged or not
+func removeOutput(outputPath string) {
+	files, err := filepath.Glob(strings.TrimSuffix(outputPath, ".mp4") + ".*")
+	if err != nil {
+		return
+	}
+	for _, f := range files {
+		err = os.Remove(f)
+		if err != nil {
+			log.Errorln(errors.Prefix("delete error", err))
+		}
+	}
+}
+
+// withoutWarnings returns the lines of the error log that are not just warnings
+func withoutWarnings(errorLog string) string {
+	var lines []string
+	for _, l := range strings.Split(errorLog, "\n") {
+		l = strings.TrimSpace(l)
+		if l == "" || strings.HasPrefix(l, "WARNING:") {
+			continue
+		}
+		lines = append(lines, l)
+	}
+	return strings.Join(lines, "\n")
+}
+
+var errorClasses = []struct {
+	substrings []string
+	err        error
+}{
+	{[]string{"HTTP Error 429", "returned non-zero exit status 8"}, ErrThrottled},
+	{[]string{"giving up after 0 fragment retries"}, ErrUnavailableFragments},
+	{[]string{"not made this video available in your country", "not available from your location", "geo restriction"}, ErrGeoBlocked},
+	{[]string{"Sign in to confirm your age", "age-restricted", "inappropriate for some users"}, ErrAgeRestricted},
+	{[]string{"File is larger than max-filesize"}, ErrTooBig},
+	{[]string{"does not pass filter duration"}, ErrTooLong},
+}
+
+// classifyError maps the error output of the tool to one of the known download errors. The original error line is
+// preserved in the message so failure reasons stay meaningful.
+func classifyError(errorLog string, exitErr error) error {
+	errorLog = strings.TrimSpace(errorLog)
+	for _, c := range errorClasses {
+		for _, s := range c.substrings {
+			if strings.Contains(errorLog, s) {
+				return errors.Prefix(firstErrorLine(errorLog), c.err)
+			}
+		}
+	}
+	if errorLog == "" {
+		return errors.Err(exitErr)
+	}
+	return errors.Err(errorLog)
+}
+
+func firstErrorLine(errorLog string) string {
+	for _, l := range strings.Split(errorLog, "\n") {
+		if strings.HasPrefix(l, "ERROR:") {
+			return l
+		}
+	}
+	return strings.Split(errorLog, "\n")[0]
+}
+
+// youtube-dl progress lines look like "[download]  12.3% of ~45.67MiB at  1.23MiB/s ETA 00:12"
+var progressRegexp = regexp.MustCompile(`^\[download\]\s+([\d.]+)% of\s+~?\s*([\d.]+)([KMGT]?i?B)(?:\s+at\s+([\d.]+)([KMGT]?i?B)/s)?(?:\s+ETA\s+([\d:]+))?`)
+
+func (y *ytdl) parseProgress(line string) (Progress, bool) {
+	if strings.HasPrefix(line, progressPrefix) {
+		return parseProgressJSON(strings.TrimPrefix(line, progressPrefix))
+	}
+	return parseProgressLine(line)
+}
+
+func parseProgressJSON(raw string) (Progress, bool) {
+	var p struct {
+		DownloadedBytes    *float64 `json:"downloaded_bytes"`
+		TotalBytes         *float64 `json:"total_bytes"`
+		TotalBytesEstimate *float64 `json:"total_bytes_estimate"`
+		Speed              *float64 `json:"speed"`
+		ETA                *float64 `json:"eta"`
+	}
+	err := json.Unmarshal([]byte(raw), &p)
+	if err != nil {
+		return Progress{}, false
+	}
+	var progress Progress
+	if p.DownloadedBytes != nil {
+		progress.DownloadedBytes = int64(*p.DownloadedBytes)
+	}
+	if p.TotalBytes != nil {
+		progress.TotalBytes = int64(*p.TotalBytes)
+	} else if p.TotalBytesEstimate != nil {
+		progress.TotalBytes = int64(*p.TotalBytesEstimate)
+	}
+	if p.Speed != nil {
+		progress.Speed = *p.Speed
+	}
+	if p.ETA != nil {
+		progress.ETA = time.Duration(*p.ETA) * time.Second
+	}
+	return progress, true
+}
+
+func parseProgressLine(line string) (Progress, bool) {
+	m := progressRegexp.FindStringSubmatch(line)
+	if m == nil {
+		return Progress{}, false
+	}
+	percent, _ := strconv.ParseFloat(m[1], 64)
+	total := parseSize(m[2], m[3])
+	progress := Progress{
+		TotalBytes:      int64(total),
+		DownloadedBytes: int64(total * percent / 100),
+	}
+	if m[4] != "" {
+		progress.Speed = parseSize(m[4], m[5])
+	}
+	if m[6] != "" {
+		progress.ETA = parseClock(m[6])
+	}
+	return progress, true
+}
+
+var sizeUnits = map[string]float64{
+	"B":   1,
+	"KiB": 1 << 10,
+	"MiB": 1 << 20,
+	"GiB": 1 << 30,
+	"TiB": 1 << 40,
+	"KB":  1e3,
+	"MB":  1e6,
+	"GB":  1e9,
+	"TB":  1e12,
+}
+
+func parseSize(value string, unit string) float64 {
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	multiplier, ok := sizeUnits[unit]
+	if !ok {
+		return 0
+	}
+	return v * multiplier
+}
+
+// parseClock parses durations in the forms "SS", "MM:SS" and "HH:MM:SS"
+func parseClock(clock string) time.Duration {
+	seconds := 0
+	for _, p := range strings.Split(clock, ":") {
+		v, err := strconv.Atoi(p)
+		if err != nil {
+			return 0
+		}
+		seconds = seconds*60 + v
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,111 @@
+package downloader
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+)
+
+func TestParseProgress(t *testing.T) {
+	y := &ytdl{binary: YtDlp, progressTemplate: true}
+	cases := []struct {
+		line     string
+		expected Progress
+	}{
+		{
+			`ytsync-progress:{"status": "downloading", "downloaded_bytes": 1048576, "total_bytes": 4194304, "speed": 524288.5, "eta": 6}`,
+			Progress{DownloadedBytes: 1048576, TotalBytes: 4194304, Speed: 524288.5, ETA: 6 * time.Second},
+		},
+		{
+			`ytsync-progress:{"downloaded_bytes": 10, "total_bytes": null, "total_bytes_estimate": 100, "speed": null, "eta": null}`,
+			Progress{DownloadedBytes: 10, TotalBytes: 100},
+		},
+		{
+			"[download]  25.0% of 4.00MiB at  1.00MiB/s ETA 01:02",
+			Progress{DownloadedBytes: 1048576, TotalBytes: 4194304, Speed: 1048576, ETA: 62 * time.Second},
+		},
+		{
+			"[download] 100% of 4.00MiB in 00:03",
+			Progress{DownloadedBytes: 4194304, TotalBytes: 4194304},
+		},
+	}
+	for _, c := range cases {
+		p, ok := y.parseProgress(c.line)
+		if !ok {
+			t.Errorf("expected %s to be parsed as progress", c.line)
+			continue
+		}
+		if p != c.expected {
+			t.Errorf("expected %+v for %s, got %+v", c.expected, c.line, p)
+		}
+	}
+
+	for _, line := range []string{"[download] Destination: video.f137.mp4", "[youtube] abc: Downloading webpage"} {
+		if _, ok := y.parseProgress(line); ok {
+			t.Errorf("%s is not a progress line", line)
+		}
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	cases := []struct {
+		log      string
+		expected error
+	}{
+		{"ERROR: unable to download video data: HTTP Error 429: Too Many Requests", ErrThrottled},
+		{"WARNING: something\nERROR: The uploader has not made this video available in your country.", ErrGeoBlocked},
+		{"ERROR: Sign in to confirm your age\nThis video may be inappropriate for some users.", ErrAgeRestricted},
+		{"ERROR: fragment 1 not found, unable to continue\nERROR: giving up after 0 fragment retries", ErrUnavailableFragments},
+	}
+	for _, c := range cases {
+		err := classifyError(c.log, nil)
+		if !errors.Is(err, c.expected) {
+			t.Errorf("expected %s to be classified as %s, got %s", c.log, c.expected, err)
+		}
+		if !strings.Contains(err.Error(), "ERROR:") {
+			t.Errorf("the original error line was lost: %s", err)
+		}
+	}
+
+	err := classifyError("ERROR: This video contains content from SME, who has blocked it on copyright grounds", nil)
+	for _, c := range cases {
+		if errors.Is(err, c.expected) {
+			t.Errorf("unexpected classification %s for %s", c.expected, err)
+		}
+	}
+	if !strings.Contains(err.Error(), "This video contains content from") {
+		t.Errorf("the original error was lost: %s", err)
+	}
+}
+
+func TestRunLongOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ytsync-ytdl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// a line longer than the scanner accepts, followed by more output than a pipe holds
+	script := filepath.Join(dir, "tool")
+	err = ioutil.WriteFile(script, []byte("#!/bin/sh\nhead -c 5000000 /dev/zero | tr '\\0' a\necho\nhead -c 1000000 /dev/zero | tr '\\0' '\\n'\n"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	y := &ytdl{binary: script}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- y.run(nil, Options{OutputPath: filepath.Join(dir, "video.mp4")})
+	}()
+	select {
+	case err := <-errCh:
+		if err == nil {
+			t.Error("an unreadable output must be reported")
+		}
+	case <-time.After(30 * time.Second):
+		t.Fatal("the output of the tool must be drained")
+	}
+}
@@ -0,0 +1,214 @@
+package errclass
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+)
+
+// Class tells what has to be done about an error
+type Class int
+
+const (
+	// Retryable errors are worth retrying right away. Errors that can't be classified are retryable
+	Retryable Class = iota
+	// RetryAfterBlock errors are retried once a new block is mined
+	RetryAfterBlock
+	// RetryAfterRefill errors are retried once the wallet is refilled
+	RetryAfterRefill
+	// NoRetry errors fail the video for this sync, the next sync attempts it again
+	NoRetry
+	// PermanentVideo errors mean the video can't ever be published
+	PermanentVideo
+	// Interrupted errors are caused by the sync being stopped
+	Interrupted
+	// ChannelSkipped errors mean the sync of the channel didn't start, the channel keeps its status
+	ChannelSkipped
+	// FatalChannel errors stop the sync of the channel
+	FatalChannel
+	// FatalProcess errors require manual intervention, ytsync exits
+	FatalProcess
+)
+
+var names = map[Class]string{
+	Retryable:        "retryable",
+	RetryAfterBlock:  "retry_after_block",
+	RetryAfterRefill: "retry_after_refill",
+	NoRetry:          "no_retry",
+	PermanentVideo:   "permanent_video",
+	Interrupted:      "interrupted",
+	ChannelSkipped:   "channel_skipped",
+	FatalChannel:     "fatal_channel",
+	FatalProcess:     "fatal_process",
+}
+
+// precedence is the order patterns are matched in when a message matches several classes
+var precedence = []Class{Interrupted, FatalProcess, FatalChannel, ChannelSkipped, PermanentVideo, NoRetry, RetryAfterBlock, RetryAfterRefill, Retryable}
+
+func (c Class) String() string {
+	return names[c]
+}
+
+// StopsChannel returns true if the sync of the channel can't go on after an error of the class
+func (c Class) StopsChannel() bool {
+	return c == FatalChannel || c == FatalProcess
+}
+
+// Names returns the names of the classes, as used in the configuration
+func Names() []string {
+	n := make([]string, 0, len(precedence))
+	for _, c := range precedence {
+		n = append(n, c.String())
+	}
+	return n
+}
+
+// Parse returns the class with the given name
+func Parse(name string) (Class, error) {
+	for c, n := range names {
+		if n == name {
+			return c, nil
+		}
+	}
+	return Retryable, errors.Err("unknown error class %s (valid: %s)", name, strings.Join(Names(), ", "))
+}
+
+// Error is an error that carries its class
+type Error struct {
+	class Class
+	err   error
+}
+
+// New returns an error of the given class with no stack trace attached, for errors declared as variables
+func New(class Class, message string) error {
+	return &Error{class: class, err: errors.Base(message)}
+}
+
+// Wrap sets the class of err
+func Wrap(class Class, err error) error {
+	if err == nil {
+		return nil
+	}
+	return errors.Err(&Error{class: class, err: err})
+}
+
+func (e *Error) Error() string {
+	return e.err.Error()
+}
+
+func (e *Error) Class() Class {
+	return e.class
+}
+
+var (
+	registryMux sync.RWMutex
+	// overrides are the patterns set by the configuration, they take precedence over the built in ones
+	overrides = map[Class][]string{}
+)
+
+// Override sets the patterns of the configuration, replacing the ones set previously. They are matched before the
+// built in patterns, which allows reclassifying known errors
+func Override(patterns map[Class][]string) {
+	registryMux.Lock()
+	defer registryMux.Unlock()
+	overrides = make(map[Class][]string, len(patterns))
+	for c, p := range patterns {
+		overrides[c] = append([]string(nil), p...)
+	}
+}
+
+// Of returns the class of err: the one it was given when created, if any, or the one its message matches
+func Of(err error) Class {
+	if err == nil {
+		return Retryable
+	}
+	if e, ok := errors.Unwrap(err).(*Error); ok {
+		return e.class
+	}
+	return OfMessage(err.Error())
+}
+
+// OfMessage returns the class an error message, such as a stored failure reason, matches
+func OfMessage(message string) Class {
+	registryMux.RLock()
+	defer registryMux.RUnlock()
+	for _, patterns := range []map[Class][]string{overrides, builtin} {
+		for _, c := range precedence {
+			for _, p := range patterns[c] {
+				if strings.Contains(message, p) {
+					return c
+				}
+			}
+		}
+	}
+	return Retryable
+}
+
+// builtin are the patterns of the errors that come as messages: from the daemon, the APIs and the external tools,
+// or stored as failure reasons
+var builtin = map[Class][]string{
+	Interrupted: {
+		"interrupted by user",
+	},
+	FatalProcess: {
+		"default_wallet already exists",
+		"WALLET HAS NOT BEEN MOVED TO THE WALLET BACKUP DIR",
+		"NotEnoughFunds",
+		"no space left on device",
+		"failure uploading wallet",
+		"the channel in the wallet is different than the channel in the database",
+		"this channel does not belong to this wallet!",
+		"You already have a stream claim published under the name",
+		"Daily Limit Exceeded",
+	},
+	FatalChannel: {
+		":5279: read: connection reset by peer",
+		"Cannot publish using channel",
+		"cannot concatenate 'str' and 'NoneType' objects",
+		"more than 90% of the space has been used.",
+		"Couldn't find private key for id",
+	},
+	ChannelSkipped: {
+		"this youtube channel is being managed by another server",
+		"interrupted during daemon startup",
+	},
+	PermanentVideo: {
+		"Error extracting sts from embedded url response",
+		"Unable to extract signature tokens",
+		"the video is too big to sync, skipping for now",
+		"video is too long to process",
+		"shorts are not synced for this channel",
+		"This video contains content from",
+		"no compatible format available for this video",
+		"Watch this video on YouTube.",
+		"have blocked it on copyright grounds",
+		"giving up after 0 fragment retries",
+		"Sign in to confirm your age",
+	},
+	NoRetry: {
+		"non 200 status code received",
+		"dont know which claim to update",
+		"uploader has not made this video available in your country",
+		"download error: AccessDenied: Access Denied",
+		"Playback on other websites has been disabled by the video owner",
+		"Error in daemon: Cannot publish empty file",
+		"Client.Timeout exceeded while awaiting headers",
+		"the video must be republished as we can't get the right size",
+		"HTTP Error 403",
+		"Sorry about that",
+		"This video is not available",
+		"requested format not available",
+		"This video is unavailable",
+	},
+	RetryAfterBlock: {
+		"txn-mempool-conflict",
+		"too-long-mempool-chain",
+	},
+	RetryAfterRefill: {
+		"Not enough funds to cover this transaction",
+		"failed: Not enough funds",
+		"Error in daemon: Insufficient funds, please deposit additional LBC",
+		"Missing inputs",
+	},
+}
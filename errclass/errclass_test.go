@@ -0,0 +1,63 @@
+package errclass
+
+import (
+	"testing"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+)
+
+func TestOf(t *testing.T) {
+	tooBig := New(PermanentVideo, "the file is larger than expected")
+	for _, c := range []struct {
+		err      error
+		expected Class
+	}{
+		{nil, Retryable},
+		{errors.Err("something unexpected happened"), Retryable},
+		{errors.Err(tooBig), PermanentVideo},
+		{errors.Prefix("download failed", errors.Err(tooBig)), PermanentVideo},
+		{Wrap(FatalChannel, errors.Err("connection refused")), FatalChannel},
+		{errors.Prefix("publish failed", errors.Err("Error in daemon: NotEnoughFunds")), FatalProcess},
+		{errors.Err("txn-mempool-conflict"), RetryAfterBlock},
+		{errors.Err("this youtube channel is being managed by another server"), ChannelSkipped},
+		{errors.Err("transcoding interrupted by user"), Interrupted},
+	} {
+		if class := Of(c.err); class != c.expected {
+			t.Errorf("%v: expected %s, got %s", c.err, c.expected, class)
+		}
+	}
+	if !errors.Is(errors.Prefix("download failed", errors.Err(tooBig)), tooBig) {
+		t.Error("classified errors must still be comparable")
+	}
+}
+
+func TestOverride(t *testing.T) {
+	defer Override(nil)
+	Override(map[Class][]string{
+		Retryable:    {"HTTP Error 403"},
+		FatalChannel: {"quota exceeded"},
+	})
+	if class := OfMessage("ERROR: HTTP Error 403: Forbidden"); class != Retryable {
+		t.Errorf("the config must take precedence over the known errors, got %s", class)
+	}
+	if class := OfMessage("upload quota exceeded"); class != FatalChannel {
+		t.Errorf("expected %s, got %s", FatalChannel, class)
+	}
+
+	Override(nil)
+	if class := OfMessage("ERROR: HTTP Error 403: Forbidden"); class != NoRetry {
+		t.Errorf("overrides must be replaced, got %s", class)
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, name := range Names() {
+		class, err := Parse(name)
+		if err != nil || class.String() != name {
+			t.Errorf("%s: got %s (%v)", name, class, err)
+		}
+	}
+	if _, err := Parse("sometimes_fatal"); err == nil {
+		t.Error("unknown classes must be rejected")
+	}
+}
@@ -27,11 +27,14 @@ require (
 	github.com/shopspring/decimal v0.0.0-20191009025716-f1972eb1d1f5
 	github.com/sirupsen/logrus v1.4.2
 	github.com/spf13/cobra v0.0.5
-	github.com/spf13/pflag v1.0.5 // indirect
+	github.com/spf13/pflag v1.0.5
+	github.com/ybbus/jsonrpc v0.0.0-20180411222309-2a548b7d822d
+	go.etcd.io/bbolt v1.3.6
 	go.opencensus.io v0.22.1 // indirect
 	golang.org/x/sync v0.0.0-20190911185100-cd5d95a43a6e // indirect
 	google.golang.org/api v0.11.0
 	google.golang.org/appengine v1.6.5 // indirect
+	gopkg.in/yaml.v2 v2.2.4
 )
 
 go 1.13
@@ -9,6 +9,7 @@ import (
 	"github.com/asaskevich/govalidator"
 	"github.com/lbryio/lbry.go/v2/extras/errors"
 	"github.com/lbryio/lbry.go/v2/extras/stop"
+	"github.com/lbryio/ytsync/errclass"
 	"github.com/lbryio/ytsync/util"
 	log "github.com/sirupsen/logrus"
 )
@@ -34,7 +35,12 @@ type throttledIP struct {
 
 var ipPoolInstance *IPPool
 
+// ipPoolMux guards the creation of the pool, concurrent syncs share it
+var ipPoolMux sync.Mutex
+
 func GetIPPool(stopGrp *stop.Group) (*IPPool, error) {
+	ipPoolMux.Lock()
+	defer ipPoolMux.Unlock()
 	if ipPoolInstance != nil {
 		return ipPoolInstance, nil
 	}
@@ -170,7 +176,7 @@ func (i *IPPool) SetThrottled(ip string) {
 var ErrAllInUse = errors.Base("all IPs are in use, try again")
 var ErrAllThrottled = errors.Base("all IPs are throttled")
 var ErrResourceLock = errors.Base("error getting next ip, did you forget to lock on the resource?")
-var ErrInterruptedByUser = errors.Base("interrupted by user")
+var ErrInterruptedByUser = errclass.New(errclass.Interrupted, "interrupted by user")
 
 func (i *IPPool) nextIP(forVideo string) (*throttledIP, error) {
 	i.lock.Lock()
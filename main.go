@@ -9,30 +9,19 @@ import (
 	"github.com/lbryio/lbry.go/v2/extras/errors"
 	"github.com/lbryio/lbry.go/v2/extras/util"
 	"github.com/lbryio/ytsync/manager"
-	"github.com/lbryio/ytsync/sdk"
 	ytUtils "github.com/lbryio/ytsync/util"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var Version string
 
-const defaultMaxTries = 3
-
 var (
-	flags          sdk.SyncFlags
-	maxTries       int
-	refill         int
-	limit          int
-	syncStatus     string
-	channelID      string
-	syncFrom       int64
-	syncUntil      int64
-	concurrentJobs int
-	videosLimit    int
-	maxVideoSize   int
-	maxVideoLength float64
+	configFile  string
+	printConfig bool
+	flagConfig  = manager.DefaultConfig()
 )
 
 func main() {
@@ -46,25 +35,9 @@ func main() {
 		Args:  cobra.RangeArgs(0, 0),
 	}
 
-	cmd.Flags().BoolVar(&flags.StopOnError, "stop-on-error", false, "If a publish fails, stop all publishing and exit")
-	cmd.Flags().IntVar(&maxTries, "max-tries", defaultMaxTries, "Number of times to try a publish that fails")
-	cmd.Flags().BoolVar(&flags.TakeOverExistingChannel, "takeover-existing-channel", false, "If channel exists and we don't own it, take over the channel")
-	cmd.Flags().IntVar(&limit, "limit", 0, "limit the amount of channels to sync")
-	cmd.Flags().BoolVar(&flags.SkipSpaceCheck, "skip-space-check", false, "Do not perform free space check on startup")
-	cmd.Flags().BoolVar(&flags.SyncUpdate, "update", false, "Update previously synced channels instead of syncing new ones")
-	cmd.Flags().BoolVar(&flags.SingleRun, "run-once", false, "Whether the process should be stopped after one cycle or not")
-	cmd.Flags().BoolVar(&flags.RemoveDBUnpublished, "remove-db-unpublished", false, "Remove videos from the database that are marked as published but aren't really published")
-	cmd.Flags().BoolVar(&flags.UpgradeMetadata, "upgrade-metadata", false, "Upgrade videos if they're on the old metadata version")
-	cmd.Flags().BoolVar(&flags.DisableTransfers, "no-transfers", false, "Skips the transferring process of videos, channels and supports")
-	cmd.Flags().BoolVar(&flags.QuickSync, "quick", false, "Look up only the last 50 videos from youtube")
-	cmd.Flags().StringVar(&syncStatus, "status", "", "Specify which queue to pull from. Overrides --update")
-	cmd.Flags().StringVar(&channelID, "channelID", "", "If specified, only this channel will be synced.")
-	cmd.Flags().Int64Var(&syncFrom, "after", time.Unix(0, 0).Unix(), "Specify from when to pull jobs [Unix time](Default: 0)")
-	cmd.Flags().Int64Var(&syncUntil, "before", time.Now().AddDate(1, 0, 0).Unix(), "Specify until when to pull jobs [Unix time](Default: current Unix time)")
-	cmd.Flags().IntVar(&concurrentJobs, "concurrent-jobs", 1, "how many jobs to process concurrently")
-	cmd.Flags().IntVar(&videosLimit, "videos-limit", 1000, "how many videos to process per channel")
-	cmd.Flags().IntVar(&maxVideoSize, "max-size", 2048, "Maximum video size to process (in MB)")
-	cmd.Flags().Float64Var(&maxVideoLength, "max-length", 2.0, "Maximum video length to process (in hours)")
+	cmd.Flags().StringVar(&configFile, "config", "", "YAML file holding the settings. Environment variables and flags override it")
+	cmd.Flags().BoolVar(&printConfig, "print-config", false, "Print the settings, with the secrets masked, and exit")
+	bindFlags(cmd.Flags(), flagConfig)
 
 	if err := cmd.Execute(); err != nil {
 		fmt.Println(err)
@@ -72,118 +45,103 @@ func main() {
 	}
 }
 
-func ytSync(cmd *cobra.Command, args []string) {
-	var hostname string
-	slackToken := os.Getenv("SLACK_TOKEN")
-	if slackToken == "" {
-		log.Error("A slack token was not present in env vars! Slack messages disabled!")
-	} else {
-		var err error
-		hostname, err = os.Hostname()
-		if err != nil {
-			log.Error("could not detect system hostname")
-			hostname = "ytsync-unknown"
+// bindFlags defines the flags that override the settings of config
+func bindFlags(fs *pflag.FlagSet, config *manager.Config) {
+	c := &config.Sync
+	fs.BoolVar(&c.StopOnError, "stop-on-error", c.StopOnError, "If a publish fails, stop syncing its channel")
+	fs.IntVar(&c.MaxTries, "max-tries", c.MaxTries, "Number of times to try a publish that fails")
+	fs.BoolVar(&c.TakeOverExistingChannel, "takeover-existing-channel", c.TakeOverExistingChannel, "If channel exists and we don't own it, take over the channel")
+	fs.IntVar(&c.Limit, "limit", c.Limit, "limit the amount of channels to sync")
+	fs.BoolVar(&c.SkipSpaceCheck, "skip-space-check", c.SkipSpaceCheck, "Do not perform free space check on startup")
+	fs.BoolVar(&c.SyncUpdate, "update", c.SyncUpdate, "Update previously synced channels instead of syncing new ones")
+	fs.BoolVar(&c.SingleRun, "run-once", c.SingleRun, "Whether the process should be stopped after one cycle or not")
+	fs.BoolVar(&c.RemoveDBUnpublished, "remove-db-unpublished", c.RemoveDBUnpublished, "Remove videos from the database that are marked as published but aren't really published")
+	fs.BoolVar(&c.UpgradeMetadata, "upgrade-metadata", c.UpgradeMetadata, "Upgrade videos if they're on the old metadata version")
+	fs.BoolVar(&c.DisableTransfers, "no-transfers", c.DisableTransfers, "Skips the transferring process of videos, channels and supports")
+	fs.BoolVar(&c.QuickSync, "quick", c.QuickSync, "Look up only the last 50 videos from youtube")
+	fs.BoolVar(&c.DetectMetadataChanges, "detect-metadata-changes", c.DetectMetadataChanges, "Update the claims of published videos whose title, description, tags or thumbnail changed on youtube")
+	fs.BoolVar(&c.SyncPlaylists, "sync-playlists", c.SyncPlaylists, "Publish the public playlists of the channel as collections once its videos are synced")
+	fs.StringVar(&c.Status, "status", c.Status, "Specify which queue to pull from. Overrides --update")
+	fs.StringVar(&c.ChannelID, "channelID", c.ChannelID, "If specified, only this channel will be synced.")
+	fs.Int64Var(&c.After, "after", c.After, "Specify from when to pull jobs [Unix time](Default: 0)")
+	fs.Int64Var(&c.Before, "before", c.Before, "Specify until when to pull jobs [Unix time](Default: current Unix time)")
+	fs.IntVar(&c.ConcurrentJobs, "concurrent-jobs", c.ConcurrentJobs, "how many channels to sync concurrently. Each one needs its own daemon, see the daemons of the config file")
+	fs.IntVar(&c.ConcurrentVideos, "concurrent-videos", c.ConcurrentVideos, "how many videos of a channel to process concurrently")
+	fs.IntVar(&c.VideosLimit, "videos-limit", c.VideosLimit, "how many videos to process per channel")
+	fs.IntVar(&c.MaxVideoSize, "max-size", c.MaxVideoSize, "Maximum video size to process (in MB)")
+	fs.Float64Var(&c.MaxVideoLength, "max-length", c.MaxVideoLength, "Maximum video length to process (in hours)")
+	fs.Float64Var(&c.MaxVODLength, "max-vod-length", c.MaxVODLength, "Maximum length of the recordings of finished live broadcasts to process (in hours). 0 disables the check")
+	fs.StringVar(&c.Source, "source", c.Source, "Where to pull videos from (youtube, local, feed)")
+	fs.StringVar(&c.LocalDir, "local-dir", c.LocalDir, "Directory holding the media files and their .info.json files when using --source local")
+	fs.StringVar(&c.FeedURL, "feed-url", c.FeedURL, "Default URL of the RSS/Atom feed to mirror when using --source feed, see feed_url in the channel policies")
+	fs.StringVar(&c.Downloader, "downloader", c.Downloader, "Tool used to download videos from youtube (youtube-dl, yt-dlp)")
+	fs.StringSliceVar(&c.Qualities, "qualities", c.Qualities, "Video heights to try, in order, when downloading from youtube. Channels can override it")
+	fs.StringVar(&c.TranscodeProfile, "transcode-profile", c.TranscodeProfile, "Transcode videos that don't fit this profile before publishing them (h264, h264-720p). Disabled by default")
+	fs.BoolVar(&c.Subtitles, "subtitles", c.Subtitles, "Download the captions of youtube videos and embed them in the published files")
+	fs.BoolVar(&c.AutoSubtitles, "auto-subtitles", c.AutoSubtitles, "Also download automatically generated captions (implies --subtitles)")
+	fs.StringSliceVar(&c.SubtitleLanguages, "subtitle-languages", c.SubtitleLanguages, "Only download captions in these languages. Automatic captions default to the language of the video")
+	fs.StringVar(&c.ChannelPolicies, "channel-policies", c.ChannelPolicies, "JSON file mapping youtube channel IDs to the settings that differ from the global ones for that channel")
+	fs.StringVar(&c.DescriptionTemplate, "description-template", c.DescriptionTemplate, "File holding the text/template of claim descriptions. Channels can override it")
+	fs.BoolVar(&c.Chapters, "chapters", c.Chapters, "Write the chapters of youtube videos in the published files")
+	fs.StringVar(&c.ShortsPolicy, "shorts-policy", c.ShortsPolicy, "How to sync youtube Shorts (publish, skip, tag, channel). Shorts have their own videos limit unless published. Channels can override it")
+	fs.StringVar(&c.RemovedVideosPolicy, "removed-videos-policy", c.RemovedVideosPolicy, "What to do with the claims of videos deleted or made private on youtube (keep, unlist, abandon). Channels can override it")
+}
+
+// loadConfig reads the config file and the environment, then applies the flags that were set on the command line
+func loadConfig(cmd *cobra.Command) (*manager.Config, error) {
+	config, err := manager.LoadConfig(configFile)
+	if err != nil {
+		return nil, err
+	}
+	overrides := pflag.NewFlagSet("overrides", pflag.ContinueOnError)
+	bindFlags(overrides, config)
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		override := overrides.Lookup(f.Name)
+		if override == nil || err != nil {
+			return
 		}
-		if len(hostname) > 30 {
-			hostname = hostname[0:30]
+		if slice, ok := f.Value.(pflag.SliceValue); ok {
+			err = override.Value.(pflag.SliceValue).Replace(slice.GetSlice())
+		} else {
+			err = override.Value.Set(f.Value.String())
 		}
-
-		util.InitSlack(os.Getenv("SLACK_TOKEN"), os.Getenv("SLACK_CHANNEL"), hostname)
-	}
-
-	if syncStatus != "" && !util.InSlice(syncStatus, manager.SyncStatuses) {
-		log.Errorf("status must be one of the following: %v\n", manager.SyncStatuses)
-		return
+	})
+	if err != nil {
+		return nil, errors.Err(err)
 	}
+	return config, nil
+}
 
-	if flags.StopOnError && maxTries != defaultMaxTries {
-		log.Errorln("--stop-on-error and --max-tries are mutually exclusive")
+func ytSync(cmd *cobra.Command, args []string) {
+	config, err := loadConfig(cmd)
+	if err != nil {
+		log.Errorln(err.Error())
 		return
 	}
-	if maxTries < 1 {
-		log.Errorln("setting --max-tries less than 1 doesn't make sense")
+	if printConfig {
+		fmt.Print(config.Redacted())
 		return
 	}
 
-	if limit < 0 {
-		log.Errorln("setting --limit less than 0 (unlimited) doesn't make sense")
-		return
+	if config.SlackToken == "" {
+		log.Error("A slack token was not present in env vars! Slack messages disabled!")
+	} else {
+		util.InitSlack(config.SlackToken, config.SlackChannel, config.Hostname)
 	}
 
-	apiURL := os.Getenv("LBRY_WEB_API")
-	apiToken := os.Getenv("LBRY_API_TOKEN")
-	youtubeAPIKey := os.Getenv("YOUTUBE_API_KEY")
-	lbrycrdString := os.Getenv("LBRYCRD_STRING")
-	awsS3ID := os.Getenv("AWS_S3_ID")
-	awsS3Secret := os.Getenv("AWS_S3_SECRET")
-	awsS3Region := os.Getenv("AWS_S3_REGION")
-	awsS3Bucket := os.Getenv("AWS_S3_BUCKET")
-	if apiURL == "" {
-		log.Errorln("An API URL was not defined. Please set the environment variable LBRY_WEB_API")
-		return
-	}
-	if apiToken == "" {
-		log.Errorln("An API Token was not defined. Please set the environment variable LBRY_API_TOKEN")
-		return
-	}
-	if youtubeAPIKey == "" {
-		log.Errorln("A Youtube API key was not defined. Please set the environment variable YOUTUBE_API_KEY")
-		return
-	}
-	if awsS3ID == "" {
-		log.Errorln("AWS S3 ID credentials were not defined. Please set the environment variable AWS_S3_ID")
-		return
-	}
-	if awsS3Secret == "" {
-		log.Errorln("AWS S3 Secret credentials were not defined. Please set the environment variable AWS_S3_SECRET")
-		return
-	}
-	if awsS3Region == "" {
-		log.Errorln("AWS S3 Region was not defined. Please set the environment variable AWS_S3_REGION")
-		return
-	}
-	if awsS3Bucket == "" {
-		log.Errorln("AWS S3 Bucket was not defined. Please set the environment variable AWS_S3_BUCKET")
+	err = config.Validate()
+	if err != nil {
+		log.Errorln(err.Error())
 		return
 	}
-	if lbrycrdString == "" {
+	if config.LbrycrdString == "" {
 		log.Infoln("Using default (local) lbrycrd instance. Set LBRYCRD_STRING if you want to use something else")
 	}
+	ytUtils.SetEnvironment(config.Environment)
+	log.Debugf("running with the following settings:\n%s", config.Redacted())
 
-	blobsDir := ytUtils.GetBlobsDir()
-
-	syncProperties := &sdk.SyncProperties{
-		SyncFrom:         syncFrom,
-		SyncUntil:        syncUntil,
-		YoutubeChannelID: channelID,
-	}
-	apiConfig := &sdk.APIConfig{
-		YoutubeAPIKey: youtubeAPIKey,
-		ApiURL:        apiURL,
-		ApiToken:      apiToken,
-		HostName:      hostname,
-	}
-	sm := manager.NewSyncManager(
-		flags,
-		maxTries,
-		refill,
-		limit,
-		concurrentJobs,
-		concurrentJobs,
-		blobsDir,
-		videosLimit,
-		maxVideoSize,
-		lbrycrdString,
-		awsS3ID,
-		awsS3Secret,
-		awsS3Region,
-		awsS3Bucket,
-		syncStatus,
-		syncProperties,
-		apiConfig,
-		maxVideoLength,
-	)
-	err := sm.Start()
+	sm := manager.NewSyncManager(config)
+	err = sm.Start()
 	if err != nil {
 		ytUtils.SendErrorToSlack(errors.FullTrace(err))
 	}
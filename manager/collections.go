@@ -0,0 +1,227 @@
+package manager
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	"github.com/lbryio/lbry.go/v2/extras/jsonrpc"
+
+	"github.com/lbryio/ytsync/sdk"
+	"github.com/lbryio/ytsync/sources"
+	"github.com/lbryio/ytsync/thumbs"
+	logUtils "github.com/lbryio/ytsync/util"
+
+	log "github.com/sirupsen/logrus"
+	rpc "github.com/ybbus/jsonrpc"
+)
+
+const collectionAmount = 0.01
+
+// collectionsClient calls the collection endpoints of lbrynet, which are not exposed by the jsonrpc client
+type collectionsClient struct {
+	conn rpc.RPCClient
+}
+
+func newCollectionsClient(address string) *collectionsClient {
+	if address == "" {
+		address = "http://localhost:" + strconv.Itoa(jsonrpc.DefaultPort)
+	}
+	return &collectionsClient{
+		conn: rpc.NewClientWithOpts(address, &rpc.RPCClientOpts{
+			HTTPClient: &http.Client{Timeout: 40 * time.Minute},
+		}),
+	}
+}
+
+type collectionOptions struct {
+	Title        string
+	Description  string
+	ThumbnailURL string
+	ChannelID    string
+	ClaimAddress string
+	AccountID    string
+}
+
+func (o collectionOptions) params() map[string]interface{} {
+	params := map[string]interface{}{
+		"title":               o.Title,
+		"description":         o.Description,
+		"channel_id":          o.ChannelID,
+		"claim_address":       o.ClaimAddress,
+		"funding_account_ids": []string{o.AccountID},
+		"blocking":            true,
+	}
+	if o.ThumbnailURL != "" {
+		params["thumbnail_url"] = o.ThumbnailURL
+	}
+	return params
+}
+
+func (c *collectionsClient) call(command string, params map[string]interface{}) (*jsonrpc.TransactionSummary, error) {
+	log.Debugf("jsonrpc: %s %+v", command, params)
+	r, err := c.conn.Call(command, params)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	if r.Error != nil {
+		return nil, errors.Err("Error in daemon: " + r.Error.Message)
+	}
+	response := new(jsonrpc.TransactionSummary)
+	err = jsonrpc.Decode(r.Result, response)
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Outputs) == 0 {
+		return nil, errors.Err("%s returned no outputs", command)
+	}
+	return response, nil
+}
+
+func (c *collectionsClient) create(name string, claimIDs []string, options collectionOptions) (*jsonrpc.TransactionSummary, error) {
+	params := options.params()
+	params["name"] = name
+	params["bid"] = fmt.Sprintf("%.6f", collectionAmount)
+	params["claims"] = claimIDs
+	return c.call("collection_create", params)
+}
+
+func (c *collectionsClient) update(claimID string, claimIDs []string, options collectionOptions) (*jsonrpc.TransactionSummary, error) {
+	params := options.params()
+	params["claim_id"] = claimID
+	params["claims"] = claimIDs
+	params["clear_claims"] = true
+	return c.call("collection_update", params)
+}
+
+// transfer moves the collection claim to address, leaving its content as it is
+func (c *collectionsClient) transfer(claimID string, address string, accountID string) (*jsonrpc.TransactionSummary, error) {
+	return c.call("collection_update", map[string]interface{}{
+		"claim_id":            claimID,
+		"claim_address":       address,
+		"bid":                 "0.005",
+		"funding_account_ids": []string{accountID},
+		"blocking":            true,
+	})
+}
+
+func sameItems(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// syncCollections publishes the playlists of the channel as collections of the claims that were synced.
+// Collections are only updated when what's published of them changed since the last run, the collections that were
+// transferred are left alone as they're not in the wallet anymore.
+func (s *Sync) syncCollections() error {
+	playlistSource, ok := s.source.(sources.PlaylistSource)
+	if !ok {
+		log.Infof("the %s source doesn't support playlists, skipping collections", s.config.Sync.Source)
+		return nil
+	}
+	playlists, err := playlistSource.ListPlaylists(s.YoutubeChannelID)
+	if err != nil {
+		return err
+	}
+	synced, err := s.Manager.jobStore.FetchSyncedCollections(s.YoutubeChannelID)
+	if err != nil {
+		return err
+	}
+	account, err := s.getDefaultAccount()
+	if err != nil {
+		return err
+	}
+	client := newCollectionsClient(s.instance.Address)
+
+	for _, playlist := range playlists {
+		if s.IsInterrupted() {
+			return errors.Err("interrupted by user")
+		}
+		var claimIDs []string
+		s.syncedVideosMux.RLock()
+		for _, videoID := range playlist.VideoIDs {
+			if sv, ok := s.syncedVideos[videoID]; ok && sv.Published && sv.ClaimID != "" {
+				claimIDs = append(claimIDs, sv.ClaimID)
+			}
+		}
+		s.syncedVideosMux.RUnlock()
+		if len(claimIDs) == 0 {
+			continue
+		}
+
+		existing, exists := synced[playlist.ID]
+		if exists && existing.Transferred {
+			continue
+		}
+		if exists && existing.ClaimID != "" && existing.Title == playlist.Title && existing.Description == playlist.Description &&
+			existing.ThumbnailURL == playlist.ThumbnailURL && sameItems(existing.Items, claimIDs) {
+			continue
+		}
+
+		options := collectionOptions{
+			Title:        playlist.Title,
+			Description:  playlist.Description,
+			ChannelID:    s.lbryChannelID,
+			ClaimAddress: s.claimAddress,
+			AccountID:    account,
+		}
+		if playlist.ThumbnailURL != "" {
+			options.ThumbnailURL, err = thumbs.MirrorThumbnail(playlist.ThumbnailURL, playlist.ID, s.Manager.GetS3AWSConfig())
+			if err != nil {
+				return errors.Prefix("thumbnail error", err)
+			}
+		}
+
+		collection := sdk.SyncedCollection{
+			PlaylistID:   playlist.ID,
+			Title:        playlist.Title,
+			Description:  playlist.Description,
+			ThumbnailURL: playlist.ThumbnailURL,
+			Items:        claimIDs,
+		}
+		s.walletMux.RLock()
+		if exists && existing.ClaimID != "" {
+			_, err = client.update(existing.ClaimID, claimIDs, options)
+			collection.ClaimID = existing.ClaimID
+			collection.ClaimName = existing.ClaimName
+		} else {
+			collection.ClaimID, collection.ClaimName, err = s.createCollection(client, playlist.Title, claimIDs, options)
+		}
+		s.walletMux.RUnlock()
+		if err != nil {
+			logUtils.SendErrorToSlack("(%s) failed to sync playlist %s as a collection: %s", s.YoutubeChannelID, playlist.ID, err.Error())
+			continue
+		}
+		log.Infof("playlist %s synced as collection %s with %d items", playlist.ID, collection.ClaimID, len(claimIDs))
+
+		err = s.Manager.jobStore.MarkCollectionStatus(s.YoutubeChannelID, collection)
+		if err != nil {
+			logUtils.SendErrorToSlack("Failed to mark collection on the database: %s", errors.FullTrace(err))
+		}
+	}
+	return nil
+}
+
+func (s *Sync) createCollection(client *collectionsClient, title string, claimIDs []string, options collectionOptions) (claimID string, claimName string, err error) {
+	for {
+		name := s.namer.GetNextName(title)
+		response, err := client.create(name, claimIDs, options)
+		if err != nil {
+			if strings.Contains(err.Error(), "failed: Multiple claims (") {
+				continue
+			}
+			return "", "", err
+		}
+		return response.Outputs[0].ClaimID, name, nil
+	}
+}
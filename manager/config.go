@@ -0,0 +1,557 @@
+package manager
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lbryio/ytsync/downloader"
+	"github.com/lbryio/ytsync/errclass"
+	"github.com/lbryio/ytsync/media"
+	"github.com/lbryio/ytsync/sdk"
+	"github.com/lbryio/ytsync/sources"
+	"github.com/lbryio/ytsync/thumbs"
+	logUtils "github.com/lbryio/ytsync/util"
+	"github.com/lbryio/ytsync/wallets"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	"github.com/lbryio/lbry.go/v2/extras/stop"
+	"github.com/lbryio/lbry.go/v2/extras/util"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"gopkg.in/yaml.v2"
+)
+
+const DefaultMaxTries = 3
+
+const redacted = "********"
+
+// Config holds all the settings of ytsync. It's read from a YAML file, then environment variables and command line
+// flags override it
+type Config struct {
+	// Hostname identifies this server on slack and in the API. It defaults to the hostname of the machine
+	Hostname       string               `yaml:"hostname"`
+	SlackToken     string               `yaml:"slack_token"`
+	SlackChannel   string               `yaml:"slack_channel"`
+	APIURL         string               `yaml:"api_url"`
+	APIToken       string               `yaml:"api_token"`
+	YoutubeAPIKey  string               `yaml:"youtube_api_key"`
+	LbrycrdString  string               `yaml:"lbrycrd_string"`
+	LbrynetAddress string               `yaml:"lbrynet_address"`
+	TmpDir         string               `yaml:"tmp_dir"`
+	AWS            AWSConfig            `yaml:"aws"`
+	JobStore       JobStoreConfig       `yaml:"job_store"`
+	WalletStore    WalletStoreConfig    `yaml:"wallet_store"`
+	Thumbnails     ThumbnailsConfig     `yaml:"thumbnails"`
+	Environment    logUtils.Environment `yaml:"environment"`
+	// Daemons are the lbrynet instances the syncs run on, there must be one per concurrent job. When it's empty a single
+	// instance is described by lbrynet_address and the environment
+	Daemons []logUtils.Instance `yaml:"daemons"`
+	Sync    SyncConfig          `yaml:"sync"`
+
+	descriptionTemplate string
+	channelPolicies     map[string]sdk.ChannelPolicy
+	errorClasses        map[errclass.Class][]string
+	walletKey           []byte
+}
+
+// AWSConfig holds the default credentials of the S3 storages, the thumbnails and the wallets are kept in, and the
+// bucket of the wallets
+type AWSConfig struct {
+	ID     string `yaml:"id"`
+	Secret string `yaml:"secret"`
+	Region string `yaml:"region"`
+	Bucket string `yaml:"bucket"`
+}
+
+// JobStoreConfig tells where the channels to sync and the state of their videos are kept
+type JobStoreConfig struct {
+	// Backend is either the internal LBRY web API or a local database
+	Backend string `yaml:"backend"`
+	// Path is the file of the local database
+	Path string `yaml:"path"`
+	// Journal is the file the video statuses are kept in until they're reported
+	Journal string `yaml:"journal"`
+	// PublishJournals is the directory the claims being published are journaled in, one file per channel
+	PublishJournals string `yaml:"publish_journals"`
+	// Channels are added to the local database, if they're not in it yet
+	Channels []LocalChannel `yaml:"channels"`
+}
+
+// WalletStoreConfig tells where the wallets of the channels are kept between syncs. The S3 settings default to the
+// ones of the aws section
+type WalletStoreConfig struct {
+	// Backend is either an S3 bucket or a local directory
+	Backend string `yaml:"backend"`
+	// Path is the directory of the local backend
+	Path string `yaml:"path"`
+	// Endpoint is the URL of an S3 compatible storage, like MinIO
+	Endpoint string `yaml:"endpoint"`
+	Bucket   string `yaml:"bucket"`
+	ID       string `yaml:"id"`
+	Secret   string `yaml:"secret"`
+	// EncryptionKey is the hex encoded AES-256 key the wallets are encrypted with. They're stored as they are when it's
+	// empty
+	EncryptionKey string `yaml:"encryption_key"`
+}
+
+// ThumbnailsConfig tells where the thumbnails are mirrored to. The credentials default to the ones of the aws section
+type ThumbnailsConfig struct {
+	// Endpoint is the URL of an S3 compatible storage, like MinIO
+	Endpoint string `yaml:"endpoint"`
+	Bucket   string `yaml:"bucket"`
+	// PublicURL is the URL the bucket is served from, the claims point to it
+	PublicURL string `yaml:"public_url"`
+	ID        string `yaml:"id"`
+	Secret    string `yaml:"secret"`
+}
+
+// LocalChannel is a channel synced with the local job store
+type LocalChannel struct {
+	ChannelID string `yaml:"channel_id"`
+	// Name is the name of the LBRY channel, @ included
+	Name string `yaml:"name"`
+}
+
+// SyncConfig holds the settings that control which channels are synced and how
+type SyncConfig struct {
+	sdk.SyncFlags       `yaml:",inline"`
+	MaxTries            int      `yaml:"max_tries"`
+	Refill              int      `yaml:"refill"`
+	Limit               int      `yaml:"limit"`
+	Status              string   `yaml:"status"`
+	ChannelID           string   `yaml:"channel_id"`
+	After               int64    `yaml:"after"`
+	Before              int64    `yaml:"before"`
+	ConcurrentJobs      int      `yaml:"concurrent_jobs"`
+	ConcurrentVideos    int      `yaml:"concurrent_videos"`
+	VideosLimit         int      `yaml:"videos_limit"`
+	MaxVideoSize        int      `yaml:"max_size"`
+	MaxVideoLength      float64  `yaml:"max_length"`
+	MaxVODLength        float64  `yaml:"max_vod_length"`
+	Source              string   `yaml:"source"`
+	LocalDir            string   `yaml:"local_dir"`
+	FeedURL             string   `yaml:"feed_url"`
+	Downloader          string   `yaml:"downloader"`
+	Qualities           []string `yaml:"qualities"`
+	TranscodeProfile    string   `yaml:"transcode_profile"`
+	Subtitles           bool     `yaml:"subtitles"`
+	AutoSubtitles       bool     `yaml:"auto_subtitles"`
+	SubtitleLanguages   []string `yaml:"subtitle_languages"`
+	RemovedVideosPolicy string   `yaml:"removed_videos_policy"`
+	ShortsPolicy        string   `yaml:"shorts_policy"`
+	Chapters            bool     `yaml:"chapters"`
+	// DescriptionTemplate and ChannelPolicies are the paths of the files holding them
+	DescriptionTemplate string `yaml:"description_template"`
+	ChannelPolicies     string `yaml:"channel_policies"`
+	// ErrorClasses maps error classes to the messages of the errors that belong to them, on top of the known ones
+	ErrorClasses map[string][]string `yaml:"error_classes"`
+}
+
+// DefaultConfig returns the settings used when neither the config file, the environment nor the flags set them
+func DefaultConfig() *Config {
+	return &Config{
+		Hostname:    defaultHostname(),
+		Environment: logUtils.Environment{ReflectBlobs: true},
+		JobStore: JobStoreConfig{
+			Backend:         sdk.JobStoreAPI,
+			Journal:         filepath.Join(os.Getenv("HOME"), ".ytsync", "status_journal.jsonl"),
+			PublishJournals: filepath.Join(os.Getenv("HOME"), ".ytsync", "publish_journals"),
+		},
+		WalletStore: WalletStoreConfig{Backend: wallets.BackendS3},
+		Thumbnails:  ThumbnailsConfig{Bucket: thumbs.DefaultBucket, PublicURL: thumbs.DefaultEndpoint},
+		Sync: SyncConfig{
+			MaxTries:            DefaultMaxTries,
+			After:               time.Unix(0, 0).Unix(),
+			Before:              time.Now().AddDate(1, 0, 0).Unix(),
+			ConcurrentJobs:      1,
+			ConcurrentVideos:    1,
+			VideosLimit:         1000,
+			MaxVideoSize:        2048,
+			MaxVideoLength:      2.0,
+			Source:              sources.SourceYoutube,
+			Downloader:          downloader.YoutubeDL,
+			Qualities:           downloader.DefaultQualities,
+			RemovedVideosPolicy: RemovedPolicyKeep,
+			ShortsPolicy:        sources.ShortsPublish,
+		},
+	}
+}
+
+func defaultHostname() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "ytsync-unknown"
+	}
+	if len(hostname) > 30 {
+		hostname = hostname[0:30]
+	}
+	return hostname
+}
+
+// LoadConfig reads the config file at path over the default settings, then applies the environment variables.
+// path can be empty to only use the defaults and the environment
+func LoadConfig(path string) (*Config, error) {
+	c := DefaultConfig()
+	if path != "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yml", ".yaml", ".json":
+		case ".toml":
+			return nil, errors.Err("TOML config files are not supported, convert %s to YAML (.yml or .yaml) or JSON (.json)", path)
+		default:
+			return nil, errors.Err("unsupported config file %s: only YAML (.yml or .yaml) and JSON (.json) files are supported", path)
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, errors.Prefix("could not read the config file", err)
+		}
+		err = yaml.UnmarshalStrict(content, c)
+		if err != nil {
+			return nil, errors.Prefix("could not parse the config file "+path, err)
+		}
+	}
+	err := c.applyEnv(os.LookupEnv)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+type envVar struct {
+	name   string
+	target interface{}
+}
+
+// envVars lists the environment variables that override the settings, they're the ones ytsync always used
+func (c *Config) envVars() []envVar {
+	return []envVar{
+		{"SLACK_TOKEN", &c.SlackToken},
+		{"SLACK_CHANNEL", &c.SlackChannel},
+		{"LBRY_WEB_API", &c.APIURL},
+		{"LBRY_API_TOKEN", &c.APIToken},
+		{"YOUTUBE_API_KEY", &c.YoutubeAPIKey},
+		{"LBRYCRD_STRING", &c.LbrycrdString},
+		{"LBRYNET_ADDRESS", &c.LbrynetAddress},
+		{"TMP_DIR", &c.TmpDir},
+		{"AWS_S3_ID", &c.AWS.ID},
+		{"AWS_S3_SECRET", &c.AWS.Secret},
+		{"AWS_S3_REGION", &c.AWS.Region},
+		{"AWS_S3_BUCKET", &c.AWS.Bucket},
+		{"JOB_STORE", &c.JobStore.Backend},
+		{"JOB_STORE_PATH", &c.JobStore.Path},
+		{"JOB_STORE_JOURNAL", &c.JobStore.Journal},
+		{"PUBLISH_JOURNALS_DIR", &c.JobStore.PublishJournals},
+		{"WALLET_STORE", &c.WalletStore.Backend},
+		{"WALLET_STORE_PATH", &c.WalletStore.Path},
+		{"WALLET_STORE_ENDPOINT", &c.WalletStore.Endpoint},
+		{"WALLET_STORE_BUCKET", &c.WalletStore.Bucket},
+		{"WALLET_STORE_ID", &c.WalletStore.ID},
+		{"WALLET_STORE_SECRET", &c.WalletStore.Secret},
+		{"WALLET_ENCRYPTION_KEY", &c.WalletStore.EncryptionKey},
+		{"THUMBNAILS_ENDPOINT", &c.Thumbnails.Endpoint},
+		{"THUMBNAILS_BUCKET", &c.Thumbnails.Bucket},
+		{"THUMBNAILS_PUBLIC_URL", &c.Thumbnails.PublicURL},
+		{"THUMBNAILS_ID", &c.Thumbnails.ID},
+		{"THUMBNAILS_SECRET", &c.Thumbnails.Secret},
+		{"BLOBS_DIRECTORY", &c.Environment.BlobsDir},
+		{"LBRYNET_DIR", &c.Environment.LbrynetDir},
+		{"LBRYNET_WALLETS_DIR", &c.Environment.WalletsDir},
+		{"LBRYNET_USE_DOCKER", &c.Environment.UseDocker},
+		{"LBRYNET_MODE", &c.Environment.DaemonMode},
+		{"LBRYNET_BIN", &c.Environment.LbrynetBinary},
+		{"LBRYNET_CONFIG", &c.Environment.LbrynetConfig},
+		{"REGTEST", &c.Environment.Regtest},
+		{"CLEAN_ON_STARTUP", &c.Environment.CleanOnStartup},
+		{"REFLECT_BLOBS", &c.Environment.ReflectBlobs},
+	}
+}
+
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	for _, v := range c.envVars() {
+		value, ok := lookup(v.name)
+		if !ok || value == "" {
+			continue
+		}
+		switch target := v.target.(type) {
+		case *string:
+			*target = value
+		case *bool:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return errors.Err("invalid value for %s: %s is not a boolean", v.name, value)
+			}
+			*target = b
+		}
+	}
+	return nil
+}
+
+// Validate checks the settings and loads the files they point to. All the problems found are reported at once
+func (c *Config) Validate() error {
+	var problems []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, errors.Err(format, args...).Error())
+		}
+	}
+	sc := &c.Sync
+
+	check(sc.Status == "" || util.InSlice(sc.Status, SyncStatuses), "status must be one of the following: %v", SyncStatuses)
+	check(util.InSlice(sc.Source, sources.SourceTypes), "source must be one of the following: %v", sources.SourceTypes)
+	check(util.InSlice(sc.Downloader, downloader.Backends), "downloader must be one of the following: %v", downloader.Backends)
+	check(sc.TranscodeProfile == "" || util.InSlice(sc.TranscodeProfile, media.ProfileNames()), "transcode profile must be one of the following: %v", media.ProfileNames())
+	check(util.InSlice(sc.RemovedVideosPolicy, RemovedPolicies), "removed videos policy must be one of the following: %v", RemovedPolicies)
+	check(util.InSlice(sc.ShortsPolicy, sources.ShortsPolicies), "shorts policy must be one of the following: %v", sources.ShortsPolicies)
+	check(sc.Source != sources.SourceLocal || sc.LocalDir != "", "a local directory is required when using the local source")
+	check(!sc.StopOnError || sc.MaxTries == DefaultMaxTries, "stop on error and max tries are mutually exclusive")
+	check(sc.MaxTries >= 1, "setting max tries less than 1 doesn't make sense")
+	check(sc.Limit >= 0, "setting limit less than 0 (unlimited) doesn't make sense")
+	check(sc.ConcurrentJobs >= 1, "setting concurrent jobs less than 1 doesn't make sense")
+	check(sc.ConcurrentVideos >= 1, "setting concurrent videos less than 1 doesn't make sense")
+	check(sc.ConcurrentJobs <= 1 || len(c.Daemons) >= sc.ConcurrentJobs, "%d concurrent jobs need as many daemons, %d are configured", sc.ConcurrentJobs, len(c.Daemons))
+	check(c.Environment.DaemonMode == "" || util.InSlice(c.Environment.DaemonMode, logUtils.DaemonModes), "daemon mode must be one of the following: %v", logUtils.DaemonModes)
+	processMode := c.Environment.DaemonMode == logUtils.DaemonModeProcess
+	if len(c.Daemons) > 1 {
+		seen := map[string]bool{}
+		for n, d := range c.Daemons {
+			check(d.Name != "" && d.LbrynetDir != "" && d.BlobsDir != "" && d.WalletsDir != "" && d.Address != "", "daemon %d must have a name, an address and its own lbrynet, blobs and wallets directories", n)
+			check(!processMode || (d.TCPPort != 0 && d.UDPPort != 0), "daemon %d must have its own tcp and udp ports in the %s mode", n, logUtils.DaemonModeProcess)
+			values := []string{"name " + d.Name, "address " + d.Address, "dir " + d.LbrynetDir, "dir " + d.BlobsDir, "dir " + d.WalletsDir}
+			if processMode {
+				values = append(values, fmt.Sprintf("tcp port %d", d.TCPPort), fmt.Sprintf("udp port %d", d.UDPPort))
+			}
+			for _, value := range values {
+				check(!seen[value], "daemon %d shares its %s with another daemon", n, value)
+				seen[value] = true
+			}
+		}
+	}
+	if processMode && c.Environment.LbrynetConfig != "" {
+		_, err := os.Stat(c.Environment.LbrynetConfig)
+		check(err == nil, "the lbrynet config can't be read: %v", err)
+	}
+
+	check(util.InSlice(c.JobStore.Backend, sdk.JobStores), "job store must be one of the following: %v", sdk.JobStores)
+	if c.JobStore.Backend == sdk.JobStoreLocal {
+		check(c.JobStore.Path != "", "the local job store needs a path. Please set JOB_STORE_PATH")
+		for n, ch := range c.JobStore.Channels {
+			check(ch.ChannelID != "" && strings.HasPrefix(ch.Name, "@"), "channel %d of the job store needs a channel ID and a name starting with @", n)
+		}
+	} else {
+		check(c.APIURL != "", "an API URL was not defined. Please set LBRY_WEB_API")
+		check(c.APIToken != "", "an API token was not defined. Please set LBRY_API_TOKEN")
+		check(len(c.JobStore.Channels) == 0, "channels can only be added to the local job store")
+	}
+	check(c.YoutubeAPIKey != "" || sc.Source != sources.SourceYoutube, "a Youtube API key was not defined. Please set YOUTUBE_API_KEY")
+	// the thumbnails are always mirrored, the wallets only need S3 with the S3 backend. The aws section is the fallback
+	// of both, and the region can be left out for S3 compatible storages
+	id, secret := c.Thumbnails.credentials(c.AWS)
+	check(id != "", "the S3 ID of the thumbnails was not defined. Please set AWS_S3_ID or THUMBNAILS_ID")
+	check(secret != "", "the S3 Secret of the thumbnails was not defined. Please set AWS_S3_SECRET or THUMBNAILS_SECRET")
+	check(c.AWS.Region != "" || c.Thumbnails.Endpoint != "", "AWS S3 Region was not defined. Please set AWS_S3_REGION")
+	check(c.Thumbnails.Bucket != "", "the thumbnails bucket was not defined. Please set THUMBNAILS_BUCKET")
+	check(c.Thumbnails.PublicURL != "", "the public URL of the thumbnails was not defined. Please set THUMBNAILS_PUBLIC_URL")
+	check(util.InSlice(c.WalletStore.Backend, wallets.Backends), "wallet store must be one of the following: %v", wallets.Backends)
+	if c.WalletStore.Backend == wallets.BackendLocal {
+		check(c.WalletStore.Path != "", "the local wallet store needs a path. Please set WALLET_STORE_PATH")
+	} else {
+		id, secret, bucket := c.WalletStore.s3Settings(c.AWS)
+		check(id != "", "the S3 ID of the wallet store was not defined. Please set AWS_S3_ID or WALLET_STORE_ID")
+		check(secret != "", "the S3 Secret of the wallet store was not defined. Please set AWS_S3_SECRET or WALLET_STORE_SECRET")
+		check(c.AWS.Region != "" || c.WalletStore.Endpoint != "", "AWS S3 Region was not defined. Please set AWS_S3_REGION")
+		check(bucket != "", "the wallet store bucket was not defined. Please set AWS_S3_BUCKET or WALLET_STORE_BUCKET")
+	}
+	c.walletKey = nil
+	if c.WalletStore.EncryptionKey != "" {
+		key, err := wallets.ParseKey(c.WalletStore.EncryptionKey)
+		if err != nil {
+			check(false, err.Error())
+		}
+		c.walletKey = key
+	}
+
+	c.descriptionTemplate = ""
+	if sc.DescriptionTemplate != "" {
+		content, err := ioutil.ReadFile(sc.DescriptionTemplate)
+		if err != nil {
+			check(false, "could not read the description template: %s", err.Error())
+		} else if _, err := sources.ParseDescriptionTemplate(string(content)); err != nil {
+			check(false, err.Error())
+		} else {
+			c.descriptionTemplate = string(content)
+		}
+	}
+	c.channelPolicies = nil
+	if sc.ChannelPolicies != "" {
+		policies, err := sdk.LoadChannelPolicies(sc.ChannelPolicies)
+		if err != nil {
+			check(false, err.Error())
+		}
+		c.channelPolicies = policies
+	}
+	c.errorClasses = make(map[errclass.Class][]string, len(sc.ErrorClasses))
+	for name, patterns := range sc.ErrorClasses {
+		class, err := errclass.Parse(name)
+		if err != nil {
+			check(false, err.Error())
+			continue
+		}
+		for _, p := range patterns {
+			check(p != "", "the patterns of the %s error class can't be empty", name)
+		}
+		c.errorClasses[class] = patterns
+	}
+
+	if len(problems) > 0 {
+		return errors.Err("invalid configuration: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Redacted returns the settings as YAML with the secrets masked
+func (c Config) Redacted() string {
+	for _, secret := range []*string{&c.SlackToken, &c.APIToken, &c.YoutubeAPIKey, &c.LbrycrdString, &c.AWS.ID, &c.AWS.Secret, &c.WalletStore.ID, &c.WalletStore.Secret, &c.WalletStore.EncryptionKey, &c.Thumbnails.ID, &c.Thumbnails.Secret} {
+		if *secret != "" {
+			*secret = redacted
+		}
+	}
+	out, err := yaml.Marshal(c)
+	if err != nil {
+		return err.Error()
+	}
+	return string(out)
+}
+
+// daemons returns the instances the concurrent jobs run on
+func (c *Config) daemons() []logUtils.Instance {
+	if len(c.Daemons) == 0 {
+		return []logUtils.Instance{{
+			Name:       logUtils.DefaultInstanceName,
+			Address:    c.LbrynetAddress,
+			LbrynetDir: c.Environment.LbrynetDir,
+			BlobsDir:   c.Environment.BlobsDir,
+			WalletsDir: c.Environment.WalletsDir,
+		}}
+	}
+	jobs := c.Sync.ConcurrentJobs
+	if jobs < 1 || jobs > len(c.Daemons) {
+		jobs = len(c.Daemons)
+	}
+	return c.Daemons[:jobs]
+}
+
+func (c *Config) syncProperties() *sdk.SyncProperties {
+	return &sdk.SyncProperties{
+		SyncFrom:         c.Sync.After,
+		SyncUntil:        c.Sync.Before,
+		YoutubeChannelID: c.Sync.ChannelID,
+	}
+}
+
+// openJobStore opens the backend of the job store. The channels of the config are added to the local one.
+// The calls to the API are interrupted once grp is stopped
+func (c *Config) openJobStore(grp *stop.Group) (sdk.JobStore, error) {
+	if c.JobStore.Backend != sdk.JobStoreLocal {
+		return &sdk.APIConfig{
+			ApiURL:   c.APIURL,
+			ApiToken: c.APIToken,
+			HostName: c.Hostname,
+			Stop:     grp,
+		}, nil
+	}
+	store, err := sdk.OpenLocalStore(c.JobStore.Path, c.Hostname)
+	if err != nil {
+		return nil, err
+	}
+	for _, ch := range c.JobStore.Channels {
+		err = store.AddChannel(sdk.YoutubeChannel{ChannelId: ch.ChannelID, DesiredChannelName: ch.Name})
+		if err != nil {
+			_ = store.Close()
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+// openWalletStore creates the store of the wallets, they're encrypted when a key is set
+func (c *Config) openWalletStore() (wallets.WalletStore, error) {
+	var store wallets.WalletStore
+	if c.WalletStore.Backend == wallets.BackendLocal {
+		local, err := wallets.NewLocalStore(c.WalletStore.Path)
+		if err != nil {
+			return nil, err
+		}
+		store = local
+	} else {
+		id, secret, bucket := c.WalletStore.s3Settings(c.AWS)
+		s3Store, err := wallets.NewS3Store(aws.Config{
+			Credentials: credentials.NewStaticCredentials(id, secret, ""),
+			Region:      aws.String(s3Region(c.AWS.Region, c.WalletStore.Endpoint)),
+		}, c.WalletStore.Endpoint, bucket)
+		if err != nil {
+			return nil, err
+		}
+		store = s3Store
+	}
+	if c.walletKey == nil {
+		return store, nil
+	}
+	return wallets.NewEncryptedStore(store, c.walletKey)
+}
+
+// s3Settings returns the credentials and the bucket of the S3 backend, the ones of the aws section are used for those
+// that aren't set
+func (w WalletStoreConfig) s3Settings(defaults AWSConfig) (id string, secret string, bucket string) {
+	id, secret, bucket = w.ID, w.Secret, w.Bucket
+	if id == "" || secret == "" {
+		id, secret = defaults.ID, defaults.Secret
+	}
+	if bucket == "" {
+		bucket = defaults.Bucket
+	}
+	return id, secret, bucket
+}
+
+func (t ThumbnailsConfig) credentials(defaults AWSConfig) (id string, secret string) {
+	if t.ID == "" || t.Secret == "" {
+		return defaults.ID, defaults.Secret
+	}
+	return t.ID, t.Secret
+}
+
+// s3Region returns the region of an S3 storage. S3 compatible storages usually ignore it, but the SDK needs one
+func s3Region(region string, endpoint string) string {
+	if region == "" && endpoint != "" {
+		return "us-east-1"
+	}
+	return region
+}
+
+// thumbnailsAWSConfig returns the settings of the S3 storage the thumbnails are mirrored to
+func (c *Config) thumbnailsAWSConfig() aws.Config {
+	id, secret := c.Thumbnails.credentials(c.AWS)
+	config := aws.Config{
+		Credentials: credentials.NewStaticCredentials(id, secret, ""),
+		Region:      aws.String(s3Region(c.AWS.Region, c.Thumbnails.Endpoint)),
+	}
+	if c.Thumbnails.Endpoint != "" {
+		config.Endpoint = aws.String(c.Thumbnails.Endpoint)
+		config.S3ForcePathStyle = aws.Bool(true)
+	}
+	return config
+}
+
+func (c *Config) subtitleOptions() *downloader.SubtitleOptions {
+	if !c.Sync.Subtitles && !c.Sync.AutoSubtitles {
+		return nil
+	}
+	return &downloader.SubtitleOptions{
+		Languages: c.Sync.SubtitleLanguages,
+		Auto:      c.Sync.AutoSubtitles,
+	}
+}
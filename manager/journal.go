@@ -0,0 +1,268 @@
+package manager
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lbryio/ytsync/sdk"
+	"github.com/lbryio/ytsync/sources"
+	logUtils "github.com/lbryio/ytsync/util"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	"github.com/lbryio/lbry.go/v2/extras/jsonrpc"
+	"github.com/lbryio/lbry.go/v2/extras/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// journalEntry is a line of a publish journal. An entry without a claim ID is the intent to publish the claim, one with
+// a claim ID is the claim that was published and a done entry means the claim is in the job store
+type journalEntry struct {
+	VideoID   string `json:"video_id"`
+	ClaimName string `json:"claim_name"`
+	Txid      string `json:"txid,omitempty"`
+	ClaimID   string `json:"claim_id,omitempty"`
+	Done      bool   `json:"done,omitempty"`
+	Time      int64  `json:"time"`
+}
+
+// publishJournal is the write-ahead log of the claims published for a channel. It's what tells, after a crash, which
+// claims were created without being recorded in the job store
+type publishJournal struct {
+	path string
+	mux  sync.Mutex
+	file *os.File
+}
+
+// openPublishJournal opens the journal of the channel in dir, creating it if needed
+func openPublishJournal(dir string, channelID string) (*publishJournal, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	j := &publishJournal{path: filepath.Join(dir, channelID+".jsonl")}
+	j.file, err = os.OpenFile(j.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	return j, nil
+}
+
+func (j *publishJournal) Close() error {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+	return errors.Err(j.file.Close())
+}
+
+// record appends the entry and makes sure it's on disk before returning
+func (j *publishJournal) record(e journalEntry) error {
+	e.Time = time.Now().Unix()
+	line, err := json.Marshal(e)
+	if err != nil {
+		return errors.Err(err)
+	}
+	j.mux.Lock()
+	defer j.mux.Unlock()
+	_, err = j.file.Write(append(line, '\n'))
+	if err == nil {
+		err = j.file.Sync()
+	}
+	return errors.Err(err)
+}
+
+// pending returns the last entry of every video whose claim isn't known to be in the job store, ordered by video
+func (j *publishJournal) pending() ([]journalEntry, error) {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+	f, err := os.Open(j.path)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	defer f.Close()
+	last := make(map[string]journalEntry)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e journalEntry
+		err := json.Unmarshal(scanner.Bytes(), &e)
+		if err != nil {
+			// only the last line can be broken, when ytsync died while writing it. The claim wasn't created yet
+			log.Errorf("skipping a broken line of the publish journal: %s", err.Error())
+			continue
+		}
+		last[e.VideoID] = e
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Err(err)
+	}
+	entries := make([]journalEntry, 0, len(last))
+	for _, e := range last {
+		if !e.Done {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, k int) bool {
+		return entries[i].VideoID < entries[k].VideoID
+	})
+	return entries, nil
+}
+
+// compact replaces the journal with the given entries
+func (j *publishJournal) compact(entries []journalEntry) error {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+	tmpPath := j.path + ".tmp"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return errors.Err(err)
+	}
+	w := bufio.NewWriter(tmp)
+	for _, e := range entries {
+		line, err := json.Marshal(e)
+		if err != nil {
+			_ = tmp.Close()
+			return errors.Err(err)
+		}
+		_, _ = w.Write(append(line, '\n'))
+	}
+	err = w.Flush()
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.Err(err)
+	}
+	err = os.Rename(tmpPath, j.path)
+	if err != nil {
+		return errors.Err(err)
+	}
+	_ = j.file.Close()
+	j.file, err = os.OpenFile(j.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	return errors.Err(err)
+}
+
+// forVideo returns the journal of a single video, as used by the sources
+func (j *publishJournal) forVideo(videoID string) *videoJournal {
+	return &videoJournal{journal: j, videoID: videoID}
+}
+
+// videoJournal implements sources.PublishJournal for a video
+type videoJournal struct {
+	journal   *publishJournal
+	videoID   string
+	claimName string
+}
+
+var _ sources.PublishJournal = (*videoJournal)(nil)
+
+func (v *videoJournal) Intent(claimName string) error {
+	v.claimName = claimName
+	return v.journal.record(journalEntry{VideoID: v.videoID, ClaimName: claimName})
+}
+
+func (v *videoJournal) Outcome(claimName string, txid string, claimID string) error {
+	return v.journal.record(journalEntry{VideoID: v.videoID, ClaimName: claimName, Txid: txid, ClaimID: claimID})
+}
+
+// done records that the claim of the video is in the job store. It does nothing if no claim was created
+func (v *videoJournal) done() error {
+	if v.claimName == "" {
+		return nil
+	}
+	return v.journal.record(journalEntry{VideoID: v.videoID, ClaimName: v.claimName, Done: true})
+}
+
+// journaledClaim returns the claim the entry is about among the claims of the channel, nil if it wasn't created
+func journaledClaim(e journalEntry, claims []jsonrpc.Claim) *jsonrpc.Claim {
+	for i, c := range claims {
+		if e.ClaimID != "" && c.ClaimID == e.ClaimID {
+			return &claims[i]
+		}
+		if e.ClaimID == "" && c.Name == e.ClaimName {
+			return &claims[i]
+		}
+	}
+	return nil
+}
+
+// reconcilePublishJournal records in the job store the claims that were published by an earlier run that didn't get
+// to record them, then empties the journal of everything that was settled
+func (s *Sync) reconcilePublishJournal() error {
+	entries, err := s.publishJournal.pending()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	claims, err := s.getClaims(false)
+	if err != nil {
+		return err
+	}
+	return s.publishJournal.compact(s.recoverJournaledClaims(entries, claims))
+}
+
+// recoverJournaledClaims reports the claims of the entries that are in the wallet but not in the job store. It returns
+// the entries that aren't settled yet, the ones whose status could be lost
+func (s *Sync) recoverJournaledClaims(entries []journalEntry, claims []jsonrpc.Claim) []journalEntry {
+	var unsettled, recovered []journalEntry
+	for _, e := range entries {
+		c := journaledClaim(e, claims)
+		if c == nil {
+			if e.ClaimID != "" {
+				log.Warnf("%s: claim %s of the publish journal isn't in the wallet, leaving it to the integrity check", e.VideoID, e.ClaimID)
+			} else {
+				log.Infof("%s: the publish of %s was interrupted before the claim was created", e.VideoID, e.ClaimName)
+			}
+			continue
+		}
+		s.syncedVideosMux.RLock()
+		sv, ok := s.syncedVideos[e.VideoID]
+		s.syncedVideosMux.RUnlock()
+		if ok && sv.Published && sv.ClaimID == c.ClaimID {
+			continue
+		}
+		size, err := c.GetStreamSizeByMagic()
+		if err != nil {
+			size = 0
+		}
+		log.Infof("%s: recovering claim %s (%s) from the publish journal", e.VideoID, c.ClaimID, c.Name)
+		settled := journalEntry{VideoID: e.VideoID, ClaimName: c.Name, ClaimID: c.ClaimID, Time: e.Time}
+		err = s.Manager.reporter.Report(sdk.VideoStatus{
+			ChannelID:       s.YoutubeChannelID,
+			VideoID:         e.VideoID,
+			Status:          VideoStatusPublished,
+			ClaimID:         c.ClaimID,
+			ClaimName:       c.Name,
+			Size:            util.PtrToInt64(int64(size)),
+			MetaDataVersion: LatestMetadataVersion,
+			IsTransferred:   util.PtrToBool(s.shouldTransfer()),
+		})
+		if err != nil {
+			log.Errorf("%s: could not record claim %s: %s", e.VideoID, c.ClaimID, err.Error())
+			unsettled = append(unsettled, settled)
+			continue
+		}
+		s.AppendSyncedVideo(e.VideoID, true, "", c.Name, c.ClaimID, LatestMetadataVersion, int64(size))
+		recovered = append(recovered, settled)
+	}
+	if len(recovered) > 0 && !s.Manager.reporter.Durable() {
+		// the claims stay in the publish journal until their statuses can't be lost anymore
+		err := s.Manager.reporter.Flush()
+		if err != nil {
+			log.Errorf("could not report the recovered claims yet, they stay in the publish journal: %s", err.Error())
+			return append(unsettled, recovered...)
+		}
+	}
+	if len(recovered) > 0 {
+		logUtils.SendInfoToSlack("(%s) %d claims published by an interrupted run were recovered from the publish journal", s.YoutubeChannelID, len(recovered))
+	}
+	return unsettled
+}
@@ -0,0 +1,108 @@
+package manager
+
+import (
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/lbryio/ytsync/sdk"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	"github.com/lbryio/lbry.go/v2/extras/jsonrpc"
+)
+
+func TestPublishJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ytsync-journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	j, err := openPublishJournal(dir, "UCaaa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// v1 was recorded in the job store, v2 was published but not recorded and v3 crashed before being published
+	v1 := j.forVideo("v1")
+	must(t, v1.Intent("video-1"))
+	must(t, v1.Outcome("video-1", "tx1", "c1"))
+	must(t, v1.done())
+	v2 := j.forVideo("v2")
+	must(t, v2.Intent("video-2"))
+	must(t, v2.Outcome("video-2", "tx2", "c2"))
+	must(t, j.forVideo("v3").Intent("video-3"))
+	must(t, j.forVideo("v4").done())
+	must(t, j.Close())
+
+	j, err = openPublishJournal(dir, "UCaaa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	pending, err := j.pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 2 || pending[0].VideoID != "v2" || pending[1].VideoID != "v3" {
+		t.Fatalf("expected v2 and v3 to be pending, got %+v", pending)
+	}
+	if pending[0].ClaimID != "c2" || pending[1].ClaimID != "" {
+		t.Errorf("expected the last entry of each video, got %+v", pending)
+	}
+
+	claims := []jsonrpc.Claim{{ClaimID: "c2", Name: "video-2"}, {ClaimID: "c3", Name: "video-3"}}
+	if c := journaledClaim(pending[0], claims); c == nil || c.ClaimID != "c2" {
+		t.Errorf("expected the claim to be found by ID, got %+v", c)
+	}
+	if c := journaledClaim(pending[1], claims); c == nil || c.ClaimID != "c3" {
+		t.Errorf("expected the claim of an interrupted publish to be found by name, got %+v", c)
+	}
+	if c := journaledClaim(pending[1], claims[:1]); c != nil {
+		t.Errorf("expected no claim for a publish that never happened, got %+v", c)
+	}
+
+	must(t, j.compact(pending[1:]))
+	must(t, j.forVideo("v5").Intent("video-5"))
+	pending, err = j.pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 2 || pending[0].VideoID != "v3" || pending[1].VideoID != "v5" {
+		t.Errorf("expected the journal to be compacted, got %+v", pending)
+	}
+}
+
+// downStore is a job store that can't be reached
+type downStore struct {
+	sdk.JobStore
+}
+
+func (downStore) MarkVideoStatuses(statuses []sdk.VideoStatus) error {
+	return errors.Err("connect: connection refused")
+}
+
+func TestRecoverJournaledClaimsNotDurable(t *testing.T) {
+	s := &Sync{
+		Manager:          &SyncManager{reporter: sdk.NewStatusReporter(downStore{}, "")},
+		YoutubeChannelID: "UCaaa",
+		syncedVideos:     make(map[string]sdk.SyncedVideo),
+		syncedVideosMux:  &sync.RWMutex{},
+	}
+	entries := []journalEntry{{VideoID: "v1", ClaimName: "video-1", ClaimID: "c1"}, {VideoID: "v2", ClaimName: "video-2"}}
+	claims := []jsonrpc.Claim{{ClaimID: "c1", Name: "video-1"}}
+	unsettled := s.recoverJournaledClaims(entries, claims)
+	if len(unsettled) != 1 || unsettled[0].ClaimID != "c1" {
+		t.Errorf("the claims must stay in the journal until their status is reported, got %+v", unsettled)
+	}
+	if !s.syncedVideos["v1"].Published {
+		t.Error("the recovered claim must be known to the sync")
+	}
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
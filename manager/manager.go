@@ -2,67 +2,53 @@ package manager
 
 import (
 	"fmt"
-	"strings"
+	"os"
+	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/lbryio/ytsync/blobs_reflector"
+	"github.com/lbryio/ytsync/errclass"
 	"github.com/lbryio/ytsync/ip_manager"
-	"github.com/lbryio/ytsync/namer"
 	"github.com/lbryio/ytsync/sdk"
+	"github.com/lbryio/ytsync/thumbs"
 	logUtils "github.com/lbryio/ytsync/util"
+	"github.com/lbryio/ytsync/wallets"
 
 	"github.com/lbryio/lbry.go/v2/extras/errors"
-	"github.com/lbryio/lbry.go/v2/extras/util"
+	"github.com/lbryio/lbry.go/v2/extras/stop"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
 	log "github.com/sirupsen/logrus"
 )
 
 type SyncManager struct {
-	SyncFlags        sdk.SyncFlags
-	maxTries         int
-	refill           int
-	limit            int
-	concurrentJobs   int
-	concurrentVideos int
-	blobsDir         string
-	videosLimit      int
-	maxVideoSize     int
-	maxVideoLength   float64
-	lbrycrdString    string
-	awsS3ID          string
-	awsS3Secret      string
-	awsS3Region      string
-	syncStatus       string
-	awsS3Bucket      string
-	syncProperties   *sdk.SyncProperties
-	apiConfig        *sdk.APIConfig
+	SyncFlags      sdk.SyncFlags
+	config         *Config
+	syncProperties *sdk.SyncProperties
+	// jobStore, reporter and walletStore are opened by Start
+	jobStore    sdk.JobStore
+	reporter    *sdk.StatusReporter
+	walletStore wallets.WalletStore
+	// grp is stopped on interruption, the groups of the syncs are its children
+	grp         *stop.Group
+	idleDaemons chan logUtils.Instance
+	countMux    sync.Mutex
+	syncCount   int
+	running     int
 }
 
-func NewSyncManager(syncFlags sdk.SyncFlags, maxTries int, refill int, limit int, concurrentJobs int, concurrentVideos int, blobsDir string, videosLimit int,
-	maxVideoSize int, lbrycrdString string, awsS3ID string, awsS3Secret string, awsS3Region string, awsS3Bucket string,
-	syncStatus string, syncProperties *sdk.SyncProperties, apiConfig *sdk.APIConfig, maxVideoLength float64) *SyncManager {
+// NewSyncManager creates a manager for a validated config. The error classes and the thumbnail store of the config
+// apply to the whole process
+func NewSyncManager(config *Config) *SyncManager {
+	errclass.Override(config.errorClasses)
+	thumbs.SetStore(config.Thumbnails.Bucket, config.Thumbnails.PublicURL)
 	return &SyncManager{
-		SyncFlags:        syncFlags,
-		maxTries:         maxTries,
-		refill:           refill,
-		limit:            limit,
-		concurrentJobs:   concurrentJobs,
-		concurrentVideos: concurrentVideos,
-		blobsDir:         blobsDir,
-		videosLimit:      videosLimit,
-		maxVideoSize:     maxVideoSize,
-		maxVideoLength:   maxVideoLength,
-		lbrycrdString:    lbrycrdString,
-		awsS3ID:          awsS3ID,
-		awsS3Secret:      awsS3Secret,
-		awsS3Region:      awsS3Region,
-		awsS3Bucket:      awsS3Bucket,
-		syncStatus:       syncStatus,
-		syncProperties:   syncProperties,
-		apiConfig:        apiConfig,
+		SyncFlags:      config.Sync.SyncFlags,
+		config:         config,
+		syncProperties: config.syncProperties(),
+		grp:            stop.New(),
 	}
 }
 
@@ -87,8 +73,22 @@ const (
 	VideoStatusUpgradeFailed = "upgradefailed"
 	VideoStatusUnpublished   = "unpublished"
 	VideoStatusTranferFailed = "transferfailed"
+	// VideoStatusRemovedAtSource marks published videos that were deleted or made private on the source platform
+	VideoStatusRemovedAtSource = "removedatsource"
+	// VideoStatusDeferred marks upcoming premieres and live broadcasts, they're synced on a later run
+	VideoStatusDeferred = "deferred"
+	// VideoStatusSkipped marks the Shorts of the channels that skip them, they're synced if the policy changes
+	VideoStatusSkipped = "skipped"
 )
 
+const (
+	RemovedPolicyKeep    = "keep"    // leave the claim untouched
+	RemovedPolicyUnlist  = "unlist"  // tag the claim as unlisted
+	RemovedPolicyAbandon = "abandon" // abandon the claim
+)
+
+var RemovedPolicies = []string{RemovedPolicyKeep, RemovedPolicyUnlist, RemovedPolicyAbandon}
+
 const (
 	TransferStateNotTouched = iota
 	TransferStatePending
@@ -97,15 +97,61 @@ const (
 )
 
 func (s *SyncManager) Start() error {
+	var err error
+	s.jobStore, err = s.config.openJobStore(s.grp)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := s.jobStore.Close()
+		if err != nil {
+			log.Errorf("could not close the job store: %s", err.Error())
+		}
+	}()
+	s.walletStore, err = s.config.openWalletStore()
+	if err != nil {
+		return errors.Prefix("could not open the wallet store", err)
+	}
 
+	daemons := s.config.daemons()
 	if logUtils.ShouldCleanOnStartup() {
-		err := logUtils.CleanForStartup()
-		if err != nil {
-			return err
+		for _, d := range daemons {
+			err := d.CleanForStartup(s.config.LbrycrdString)
+			if err != nil {
+				return err
+			}
 		}
 	}
+	s.idleDaemons = make(chan logUtils.Instance, len(daemons))
+	for _, d := range daemons {
+		s.idleDaemons <- d
+	}
+
+	interruptChan := make(chan os.Signal, 1)
+	signal.Notify(interruptChan, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interruptChan)
+	go func() {
+		select {
+		case <-interruptChan:
+			log.Println("Got interrupt signal, shutting down (if publishing, will shut down after current publish)")
+			s.grp.Stop()
+		case <-s.grp.Ch():
+		}
+	}()
+	defer s.grp.Stop()
+	// the statuses are reported before stopping, unless ytsync was interrupted
+	s.reporter = sdk.NewStatusReporter(s.jobStore, s.config.JobStore.Journal)
+	err = s.reporter.Start()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := s.reporter.Close()
+		if err != nil {
+			logUtils.SendErrorToSlack("%s, they're kept in %s until the next start", err.Error(), s.config.JobStore.Journal)
+		}
+	}()
 
-	syncCount := 0
 	for {
 		err := s.checkUsedSpace()
 		if err != nil {
@@ -117,151 +163,190 @@ func (s *SyncManager) Start() error {
 
 		isSingleChannelSync := s.syncProperties.YoutubeChannelID != ""
 		if isSingleChannelSync {
-			channels, err := s.apiConfig.FetchChannels("", s.syncProperties)
+			channels, err := s.jobStore.FetchChannels("", s.syncProperties)
+			if errclass.Of(err) == errclass.Interrupted {
+				return nil
+			}
 			if err != nil {
 				return errors.Err(err)
 			}
 			if len(channels) != 1 {
 				return errors.Err("Expected 1 channel, %d returned", len(channels))
 			}
-			lbryChannelName := channels[0].DesiredChannelName
 			syncs = make([]Sync, 1)
-			syncs[0] = Sync{
-				APIConfig:            s.apiConfig,
-				YoutubeChannelID:     s.syncProperties.YoutubeChannelID,
-				LbryChannelName:      lbryChannelName,
-				lbryChannelID:        channels[0].ChannelClaimID,
-				MaxTries:             s.maxTries,
-				ConcurrentVideos:     s.concurrentVideos,
-				Refill:               s.refill,
-				Manager:              s,
-				LbrycrdString:        s.lbrycrdString,
-				AwsS3ID:              s.awsS3ID,
-				AwsS3Secret:          s.awsS3Secret,
-				AwsS3Region:          s.awsS3Region,
-				AwsS3Bucket:          s.awsS3Bucket,
-				namer:                namer.NewNamer(),
-				Fee:                  channels[0].Fee,
-				clientPublishAddress: channels[0].PublishAddress,
-				publicKey:            channels[0].PublicKey,
-				transferState:        channels[0].TransferState,
-			}
+			syncs[0] = s.newSync(channels[0])
+			syncs[0].YoutubeChannelID = s.syncProperties.YoutubeChannelID
 			shouldInterruptLoop = true
 		} else {
 			var queuesToSync []string
-			if s.syncStatus != "" {
-				queuesToSync = append(queuesToSync, s.syncStatus)
+			if s.config.Sync.Status != "" {
+				queuesToSync = append(queuesToSync, s.config.Sync.Status)
 			} else if s.SyncFlags.SyncUpdate {
 				queuesToSync = append(queuesToSync, StatusSyncing, StatusSynced)
 			} else {
 				queuesToSync = append(queuesToSync, StatusSyncing, StatusQueued)
 			}
-		queues:
+			queued := make(map[string]bool)
 			for _, q := range queuesToSync {
 				//temporary override for sync-until to give tom the time to review the channels
-				if q == StatusQueued {
+				if q == StatusQueued && s.config.JobStore.Backend == sdk.JobStoreAPI {
 					s.syncProperties.SyncUntil = time.Now().Add(-8 * time.Hour).Unix()
 				}
-				channels, err := s.apiConfig.FetchChannels(q, s.syncProperties)
+				channels, err := s.jobStore.FetchChannels(q, s.syncProperties)
+				if errclass.Of(err) == errclass.Interrupted {
+					return nil
+				}
 				if err != nil {
 					return err
 				}
+				// every daemon takes a channel from each queue, the queues are fetched again once they're all synced
+				taken := 0
 				for i, c := range channels {
-					log.Infof("There are %d channels in the \"%s\" queue", len(channels)-i, q)
-					syncs = append(syncs, Sync{
-						APIConfig:            s.apiConfig,
-						YoutubeChannelID:     c.ChannelId,
-						LbryChannelName:      c.DesiredChannelName,
-						lbryChannelID:        c.ChannelClaimID,
-						MaxTries:             s.maxTries,
-						ConcurrentVideos:     s.concurrentVideos,
-						Refill:               s.refill,
-						Manager:              s,
-						LbrycrdString:        s.lbrycrdString,
-						AwsS3ID:              s.awsS3ID,
-						AwsS3Secret:          s.awsS3Secret,
-						AwsS3Region:          s.awsS3Region,
-						AwsS3Bucket:          s.awsS3Bucket,
-						namer:                namer.NewNamer(),
-						Fee:                  c.Fee,
-						clientPublishAddress: c.PublishAddress,
-						publicKey:            c.PublicKey,
-						transferState:        c.TransferState,
-					})
-					if q != StatusFailed {
-						continue queues
+					if q != StatusFailed && taken >= len(daemons) {
+						break
 					}
+					if queued[c.ChannelId] {
+						continue
+					}
+					log.Infof("There are %d channels in the \"%s\" queue", len(channels)-i, q)
+					queued[c.ChannelId] = true
+					syncs = append(syncs, s.newSync(c))
+					taken++
 				}
 			}
 		}
 		if len(syncs) == 0 {
 			log.Infoln("No channels to sync. Pausing 5 minutes!")
-			time.Sleep(5 * time.Minute)
-		}
-		for _, sync := range syncs {
-			shouldNotCount := false
-			logUtils.SendInfoToSlack("Syncing %s (%s) to LBRY! total processed channels since startup: %d", sync.LbryChannelName, sync.YoutubeChannelID, syncCount+1)
-			err := sync.FullCycle()
-			//TODO: THIS IS A TEMPORARY WORK AROUND FOR THE STUPID IP LOCKUP BUG
-			ipPool, _ := ip_manager.GetIPPool(sync.grp)
-			if ipPool != nil {
-				ipPool.ReleaseAll()
-			}
-
-			if err != nil {
-				fatalErrors := []string{
-					"default_wallet already exists",
-					"WALLET HAS NOT BEEN MOVED TO THE WALLET BACKUP DIR",
-					"NotEnoughFunds",
-					"no space left on device",
-					"failure uploading wallet",
-					"the channel in the wallet is different than the channel in the database",
-					"this channel does not belong to this wallet!",
-					"You already have a stream claim published under the name",
-					"Daily Limit Exceeded",
-				}
-				if util.SubstringInSlice(err.Error(), fatalErrors) {
-					return errors.Prefix("@Nikooo777 this requires manual intervention! Exiting...", err)
-				}
-				shouldNotCount = strings.Contains(err.Error(), "this youtube channel is being managed by another server")
-				if !shouldNotCount {
-					logUtils.SendInfoToSlack("A non fatal error was reported by the sync process. %s\nContinuing...", err.Error())
-				}
-			}
-			err = blobs_reflector.ReflectAndClean()
-			if err != nil {
-				return errors.Prefix("@Nikooo777 something went wrong while reflecting blobs", err)
-			}
-			logUtils.SendInfoToSlack("Syncing %s (%s) reached an end. total processed channels since startup: %d", sync.LbryChannelName, sync.YoutubeChannelID, syncCount+1)
-			if !shouldNotCount {
-				syncCount++
-			}
-			if sync.IsInterrupted() || (s.limit != 0 && syncCount >= s.limit) {
-				shouldInterruptLoop = true
-				break
+			select {
+			case <-s.grp.Ch():
+				return nil
+			case <-time.After(5 * time.Minute):
 			}
 		}
-		if shouldInterruptLoop || s.SyncFlags.SingleRun {
+		interrupted, err := s.runSyncs(syncs)
+		if err != nil {
+			return err
+		}
+		if interrupted || shouldInterruptLoop || s.SyncFlags.SingleRun {
 			break
 		}
 	}
 	return nil
 }
-func (s *SyncManager) GetS3AWSConfig() aws.Config {
-	return aws.Config{
-		Credentials: credentials.NewStaticCredentials(s.awsS3ID, s.awsS3Secret, ""),
-		Region:      &s.awsS3Region,
+
+// runSyncs runs each sync on an idle daemon, so as many channels are synced at the same time as there are daemons.
+// It returns once all the syncs that were started are over. No more syncs are started after a fatal error, an
+// interruption or once the limit of channels is reached
+func (s *SyncManager) runSyncs(syncs []Sync) (interrupted bool, err error) {
+	var wg sync.WaitGroup
+	var mux sync.Mutex
+	for i := range syncs {
+		daemon := <-s.idleDaemons
+		mux.Lock()
+		stopped := err != nil || interrupted || s.limitReached()
+		mux.Unlock()
+		if stopped {
+			s.idleDaemons <- daemon
+			break
+		}
+
+		channelSync := &syncs[i]
+		channelSync.instance = daemon
+		s.countMux.Lock()
+		s.running++
+		s.countMux.Unlock()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { s.idleDaemons <- daemon }()
+			syncInterrupted, syncErr := s.runSync(channelSync)
+			mux.Lock()
+			defer mux.Unlock()
+			if syncErr != nil && err == nil {
+				err = syncErr
+			}
+			interrupted = interrupted || syncInterrupted
+		}()
 	}
+	wg.Wait()
+	return interrupted, err
 }
-func (s *SyncManager) checkUsedSpace() error {
-	usedPctile, err := GetUsedSpace(logUtils.GetBlobsDir())
+
+// limitReached returns true once the channels that were synced, or are being synced, reach the limit
+func (s *SyncManager) limitReached() bool {
+	s.countMux.Lock()
+	defer s.countMux.Unlock()
+	return s.config.Sync.Limit != 0 && s.syncCount+s.running >= s.config.Sync.Limit
+}
+
+// runSync runs a full cycle for the channel. It returns true if syncing must be stopped
+func (s *SyncManager) runSync(sync *Sync) (bool, error) {
+	s.countMux.Lock()
+	count := s.syncCount + s.running
+	s.countMux.Unlock()
+	shouldNotCount := false
+	logUtils.SendInfoToSlack("Syncing %s (%s) to LBRY on %s! total processed channels since startup: %d", sync.LbryChannelName, sync.YoutubeChannelID, sync.instance, count)
+	err := sync.FullCycle()
+	s.countMux.Lock()
+	s.running--
+	idle := s.running == 0
+	s.countMux.Unlock()
+	//TODO: THIS IS A TEMPORARY WORK AROUND FOR THE STUPID IP LOCKUP BUG
+	if idle {
+		ipPool, _ := ip_manager.GetIPPool(s.grp)
+		if ipPool != nil {
+			ipPool.ReleaseAll()
+		}
+	}
+
+	if err != nil {
+		class := errclass.Of(err)
+		if class == errclass.FatalProcess {
+			return true, errors.Prefix("@Nikooo777 this requires manual intervention! Exiting...", err)
+		}
+		shouldNotCount = class == errclass.ChannelSkipped
+		if !shouldNotCount {
+			logUtils.SendInfoToSlack("A non fatal error was reported by the sync process. %s\nContinuing...", err.Error())
+		}
+	}
+	err = blobs_reflector.ReflectAndClean(sync.instance)
 	if err != nil {
-		return errors.Err(err)
+		return true, errors.Prefix("@Nikooo777 something went wrong while reflecting blobs", err)
 	}
-	if usedPctile >= 0.90 && !s.SyncFlags.SkipSpaceCheck {
-		return errors.Err(fmt.Sprintf("more than 90%% of the space has been used. use --skip-space-check to ignore. Used: %.1f%%", usedPctile*100))
+	s.countMux.Lock()
+	if !shouldNotCount {
+		s.syncCount++
+	}
+	count = s.syncCount
+	s.countMux.Unlock()
+	logUtils.SendInfoToSlack("Syncing %s (%s) reached an end. total processed channels since startup: %d", sync.LbryChannelName, sync.YoutubeChannelID, count)
+	if sync.IsInterrupted() {
+		select {
+		case <-s.grp.Ch():
+			return true, nil
+		default:
+		}
+		// only this channel was stopped, by an error that stops it or --stop-on-error, its daemon is free for the next one
+		log.Infof("the sync of %s was stopped, continuing with the other channels", sync.YoutubeChannelID)
+	}
+	return s.limitReached(), nil
+}
+
+// GetS3AWSConfig returns the settings of the S3 storage the thumbnails are mirrored to
+func (s *SyncManager) GetS3AWSConfig() aws.Config {
+	return s.config.thumbnailsAWSConfig()
+}
+func (s *SyncManager) checkUsedSpace() error {
+	for _, d := range s.config.daemons() {
+		usedPctile, err := GetUsedSpace(d.GetBlobsDir())
+		if err != nil {
+			return errors.Err(err)
+		}
+		if usedPctile >= 0.90 && !s.SyncFlags.SkipSpaceCheck {
+			return errors.Err(fmt.Sprintf("more than 90%% of the space has been used. use --skip-space-check to ignore. Used: %.1f%%", usedPctile*100))
+		}
+		log.Infof("disk usage (%s): %.1f%%", d, usedPctile*100)
 	}
-	log.Infof("disk usage: %.1f%%", usedPctile*100)
 	return nil
 }
 
@@ -0,0 +1,109 @@
+package manager
+
+import (
+	"regexp"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	"github.com/lbryio/lbry.go/v2/extras/util"
+
+	"github.com/lbryio/ytsync/errclass"
+	"github.com/lbryio/ytsync/namer"
+	"github.com/lbryio/ytsync/sdk"
+	"github.com/lbryio/ytsync/sources"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultPolicy returns the policy made of the global settings. All of its fields are set
+func (s *SyncManager) defaultPolicy() sdk.ChannelPolicy {
+	return sdk.ChannelPolicy{
+		MaxVideoSize:        util.PtrToInt(s.config.Sync.MaxVideoSize),
+		MaxVideoLength:      util.PtrToFloat64(s.config.Sync.MaxVideoLength),
+		VideosLimit:         util.PtrToInt(s.config.Sync.VideosLimit),
+		Qualities:           s.config.Sync.Qualities,
+		DescriptionTemplate: s.config.descriptionTemplate,
+		RemovedVideosPolicy: s.config.Sync.RemovedVideosPolicy,
+		ShortsPolicy:        s.config.Sync.ShortsPolicy,
+		Skip:                &sdk.SkipRules{},
+		FeedURL:             s.config.Sync.FeedURL,
+	}
+}
+
+// channelPolicy merges the global settings with the policy of the channel sent by the API, then with the one from
+// the channel policies file
+func (s *SyncManager) channelPolicy(c sdk.YoutubeChannel) sdk.ChannelPolicy {
+	policy := s.defaultPolicy()
+	policy.Fee = c.Fee
+	policy = policy.Merge(c.Policy)
+	if p, ok := s.config.channelPolicies[c.ChannelId]; ok {
+		policy = policy.Merge(&p)
+	}
+	return policy
+}
+
+func (s *SyncManager) newSync(c sdk.YoutubeChannel) Sync {
+	return Sync{
+		YoutubeChannelID:     c.ChannelId,
+		LbryChannelName:      c.DesiredChannelName,
+		lbryChannelID:        c.ChannelClaimID,
+		Manager:              s,
+		config:               s.config,
+		namer:                namer.NewNamer(),
+		Policy:               s.channelPolicy(c),
+		clientPublishAddress: c.PublishAddress,
+		publicKey:            c.PublicKey,
+		transferState:        c.TransferState,
+	}
+}
+
+// skippedChannel returns an error if the policy excludes the channel altogether. It's checked before anything is set up
+// for the channel, which keeps its status
+func (s *Sync) skippedChannel() error {
+	if s.Policy.Skip != nil && s.Policy.Skip.Channel != "" {
+		return errclass.Wrap(errclass.ChannelSkipped, errors.Err("this channel is skipped by its policy: %s", s.Policy.Skip.Channel))
+	}
+	return nil
+}
+
+// applyPolicy validates the merged policy of the channel and prepares what's derived from it
+func (s *Sync) applyPolicy() error {
+	err := s.Policy.Validate()
+	if err != nil {
+		return err
+	}
+	if !util.InSlice(s.Policy.RemovedVideosPolicy, RemovedPolicies) {
+		return errors.Err("unknown removed videos policy: %s", s.Policy.RemovedVideosPolicy)
+	}
+	if !util.InSlice(s.Policy.ShortsPolicy, sources.ShortsPolicies) {
+		return errors.Err("unknown shorts policy: %s", s.Policy.ShortsPolicy)
+	}
+	if s.Policy.ShortsPolicy == sources.ShortsChannel && s.Policy.ShortsChannelID == "" {
+		return errors.Err("the shorts policy is %s but no channel was set for shorts", sources.ShortsChannel)
+	}
+	if s.Policy.DescriptionTemplate != "" {
+		s.descriptionTemplate, err = sources.ParseDescriptionTemplate(s.Policy.DescriptionTemplate)
+		if err != nil {
+			return err
+		}
+	}
+	s.skipPatterns = nil
+	for _, pattern := range s.Policy.Skip.TitlePatterns {
+		s.skipPatterns = append(s.skipPatterns, regexp.MustCompile(pattern))
+	}
+	return nil
+}
+
+// skippedByPolicy returns true if the policy of the channel excludes the video
+func (s *Sync) skippedByPolicy(v sources.Video) bool {
+	if util.InSlice(v.ID(), s.Policy.Skip.VideoIDs) {
+		log.Println(v.ID() + " is skipped by the channel policy")
+		return true
+	}
+	for _, pattern := range s.skipPatterns {
+		if pattern.MatchString(v.Title()) {
+			log.Printf("%s is skipped by the channel policy: its title matches %s", v.ID(), pattern.String())
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,19 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/lbryio/ytsync/errclass"
+	"github.com/lbryio/ytsync/sdk"
+)
+
+func TestSkippedChannel(t *testing.T) {
+	s := &Sync{Policy: sdk.ChannelPolicy{Skip: &sdk.SkipRules{}}}
+	if err := s.skippedChannel(); err != nil {
+		t.Errorf("the channel isn't skipped, got %v", err)
+	}
+	s.Policy.Skip.Channel = "moved to another server"
+	if err := s.skippedChannel(); errclass.Of(err) != errclass.ChannelSkipped {
+		t.Errorf("a skipped channel must not be reported as failed, got %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package manager
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	"github.com/lbryio/lbry.go/v2/extras/jsonrpc"
+	"github.com/lbryio/lbry.go/v2/extras/util"
+
+	"github.com/lbryio/ytsync/sdk"
+	logUtils "github.com/lbryio/ytsync/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const unlistedTag = "c:unlisted"
+
+type removedVideo struct {
+	VideoID string
+	ClaimID string
+	Action  string
+}
+
+// handleRemovedVideo applies the removed videos policy to the claim of a published video that was deleted or made
+// private on the source, then records the new status of the video.
+func (s *Sync) handleRemovedVideo(videoID string, sv sdk.SyncedVideo) error {
+	if sv.Transferred {
+		log.Infof("%s was removed at source but its claim was transferred, leaving it alone", videoID)
+		return nil
+	}
+	policy := s.Policy.RemovedVideosPolicy
+	log.Infof("%s was removed at source, applying the %s policy to claim %s", videoID, policy, sv.ClaimID)
+
+	claim, err := s.getClaim(sv.ClaimID)
+	if err != nil {
+		return err
+	}
+	account, err := s.getDefaultAccount()
+	if err != nil {
+		return err
+	}
+	published := claim != nil && policy != RemovedPolicyAbandon
+	if claim != nil {
+		s.walletMux.RLock()
+		switch policy {
+		case RemovedPolicyAbandon:
+			_, err = s.daemon.StreamAbandon(claim.Txid, claim.Nout, nil, true)
+		case RemovedPolicyUnlist:
+			if !util.InSlice(unlistedTag, claim.Value.GetTags()) {
+				_, err = s.daemon.StreamUpdate(claim.ClaimID, jsonrpc.StreamUpdateOptions{
+					StreamCreateOptions: &jsonrpc.StreamCreateOptions{
+						ClaimCreateOptions: jsonrpc.ClaimCreateOptions{
+							Tags:              []string{unlistedTag},
+							FundingAccountIDs: []string{account},
+						},
+					},
+				})
+			}
+		}
+		s.walletMux.RUnlock()
+		if err != nil {
+			return errors.Prefix(fmt.Sprintf("failed to %s the claim of removed video %s", policy, videoID), err)
+		}
+	}
+
+	s.syncedVideosMux.Lock()
+	sv.Published = published
+	sv.Status = VideoStatusRemovedAtSource
+	s.syncedVideos[videoID] = sv
+	s.syncedVideosMux.Unlock()
+
+	s.removedVideosMux.Lock()
+	s.removedVideos = append(s.removedVideos, removedVideo{VideoID: videoID, ClaimID: sv.ClaimID, Action: policy})
+	s.removedVideosMux.Unlock()
+
+	err = s.Manager.reporter.Report(sdk.VideoStatus{
+		ChannelID:       s.YoutubeChannelID,
+		VideoID:         videoID,
+		Status:          VideoStatusRemovedAtSource,
+		ClaimID:         sv.ClaimID,
+		ClaimName:       sv.ClaimName,
+		Size:            util.PtrToInt64(sv.Size),
+		MetaDataVersion: uint(sv.MetadataVersion),
+		IsTransferred:   util.PtrToBool(false),
+	})
+	if err != nil {
+		logUtils.SendErrorToSlack("Failed to mark video on the database: %s", errors.FullTrace(err))
+	}
+	return nil
+}
+
+// getClaim returns the claim with the given ID or nil if it doesn't exist anymore
+func (s *Sync) getClaim(claimID string) (*jsonrpc.Claim, error) {
+	if claimID == "" {
+		return nil, nil
+	}
+	searchResponse, err := s.daemon.ClaimSearch(nil, &claimID, nil, nil, 1, 20)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	if len(searchResponse.Claims) == 0 {
+		return nil, nil
+	}
+	return &searchResponse.Claims[0], nil
+}
+
+// reportRemovedVideos reports the claims affected by videos removed at source during this sync
+func (s *Sync) reportRemovedVideos() {
+	s.removedVideosMux.Lock()
+	defer s.removedVideosMux.Unlock()
+	if len(s.removedVideos) == 0 {
+		return
+	}
+	lines := make([]string, 0, len(s.removedVideos))
+	for _, rv := range s.removedVideos {
+		lines = append(lines, fmt.Sprintf("%s -> %s (%s)", rv.VideoID, rv.ClaimID, rv.Action))
+	}
+	log.Infof("%d videos were removed at source:\n%s", len(lines), strings.Join(lines, "\n"))
+	logUtils.SendInfoToSlack("(%s) %d videos were removed at source: %s", s.YoutubeChannelID, len(lines), strings.Join(lines, ", "))
+}
@@ -3,7 +3,6 @@ package manager
 import (
 	"fmt"
 	"math"
-	"net/http"
 	"strconv"
 	"time"
 
@@ -17,8 +16,6 @@ import (
 
 	"github.com/shopspring/decimal"
 	log "github.com/sirupsen/logrus"
-	"google.golang.org/api/googleapi/transport"
-	"google.golang.org/api/youtube/v3"
 )
 
 func (s *Sync) enableAddressReuse() error {
@@ -69,7 +66,7 @@ func (s *Sync) walletSetup() error {
 	}
 	log.Debugf("Starting balance is %.4f", balance)
 
-	n, err := s.CountVideos()
+	n, err := s.source.CountVideos(s.YoutubeChannelID)
 	if err != nil {
 		return err
 	}
@@ -98,8 +95,8 @@ func (s *Sync) walletSetup() error {
 
 	log.Debugf("We already allocated credits for %d published videos and %d failed videos", publishedCount, failedCount)
 
-	if videosOnYoutube > s.Manager.videosLimit {
-		videosOnYoutube = s.Manager.videosLimit
+	if videosOnYoutube > *s.Policy.VideosLimit {
+		videosOnYoutube = *s.Policy.VideosLimit
 	}
 	unallocatedVideos := videosOnYoutube - (publishedCount + failedCount)
 	channelFee := channelClaimAmount
@@ -117,8 +114,8 @@ func (s *Sync) walletSetup() error {
 		refillAmount = math.Max(math.Max(requiredBalance-balance, minimumAccountBalance-balance), minimumRefillAmount)
 	}
 
-	if s.Refill > 0 {
-		refillAmount += float64(s.Refill)
+	if s.config.Sync.Refill > 0 {
+		refillAmount += float64(s.config.Sync.Refill)
 	}
 
 	if refillAmount > 0 {
@@ -284,7 +281,7 @@ func (s *Sync) waitForNewBlock() error {
 }
 
 func (s *Sync) GenerateRegtestBlock() error {
-	lbrycrd, err := logUtils.GetLbrycrdClient(s.LbrycrdString)
+	lbrycrd, err := logUtils.GetLbrycrdClient(s.config.LbrycrdString)
 	if err != nil {
 		return errors.Prefix("error getting lbrycrd client: ", err)
 	}
@@ -362,36 +359,19 @@ func (s *Sync) ensureChannelOwnership() error {
 			return err
 		}
 	}
-	client := &http.Client{
-		Transport: &transport.APIKey{Key: s.APIConfig.YoutubeAPIKey},
-	}
-
-	service, err := youtube.New(client)
-	if err != nil {
-		return errors.Prefix("error creating YouTube service", err)
-	}
-
-	response, err := service.Channels.List("snippet,brandingSettings").Id(s.YoutubeChannelID).Do()
+	channelInfo, err := s.source.GetChannelInfo(s.YoutubeChannelID)
 	if err != nil {
-		return errors.Prefix("error getting channel details", err)
-	}
-
-	if len(response.Items) < 1 {
-		return errors.Err("youtube channel not found")
+		return err
 	}
 
-	channelInfo := response.Items[0].Snippet
-	channelBranding := response.Items[0].BrandingSettings
-
-	thumbnail := thumbs.GetBestThumbnail(channelInfo.Thumbnails)
-	thumbnailURL, err := thumbs.MirrorThumbnail(thumbnail.Url, s.YoutubeChannelID, s.Manager.GetS3AWSConfig())
+	thumbnailURL, err := thumbs.MirrorThumbnail(channelInfo.ThumbnailURL, s.YoutubeChannelID, s.Manager.GetS3AWSConfig())
 	if err != nil {
 		return err
 	}
 
 	var bannerURL *string
-	if channelBranding.Image != nil && channelBranding.Image.BannerImageUrl != "" {
-		bURL, err := thumbs.MirrorThumbnail(channelBranding.Image.BannerImageUrl, "banner-"+s.YoutubeChannelID, s.Manager.GetS3AWSConfig())
+	if channelInfo.BannerURL != "" {
+		bURL, err := thumbs.MirrorThumbnail(channelInfo.BannerURL, "banner-"+s.YoutubeChannelID, s.Manager.GetS3AWSConfig())
 		if err != nil {
 			return err
 		}
@@ -399,11 +379,8 @@ func (s *Sync) ensureChannelOwnership() error {
 	}
 
 	var languages []string = nil
-	if channelInfo.DefaultLanguage != "" {
-		if channelInfo.DefaultLanguage == "iw" {
-			channelInfo.DefaultLanguage = "he"
-		}
-		languages = []string{channelInfo.DefaultLanguage}
+	if channelInfo.Language != "" {
+		languages = []string{channelInfo.Language}
 	}
 	var locations []jsonrpc.Location = nil
 	if channelInfo.Country != "" {
@@ -439,12 +416,12 @@ func (s *Sync) ensureChannelOwnership() error {
 		return err
 	}
 	s.lbryChannelID = c.Outputs[0].ClaimID
-	return s.Manager.apiConfig.SetChannelClaimID(s.YoutubeChannelID, s.lbryChannelID)
+	return s.Manager.jobStore.SetChannelClaimID(s.YoutubeChannelID, s.lbryChannelID)
 }
 
 func (s *Sync) addCredits(amountToAdd float64) error {
 	log.Printf("Adding %f credits", amountToAdd)
-	lbrycrdd, err := logUtils.GetLbrycrdClient(s.LbrycrdString)
+	lbrycrdd, err := logUtils.GetLbrycrdClient(s.config.LbrycrdString)
 	if err != nil {
 		return err
 	}
@@ -87,7 +87,7 @@ func abandonSupports(s *Sync) (float64, error) {
 	//TODO: remove this once the SDK team fixes their RPC bugs....
 	s.daemon.SetRPCTimeout(30 * time.Second)
 	defer s.daemon.SetRPCTimeout(40 * time.Minute)
-	for i := 0; i < s.ConcurrentVideos; i++ {
+	for i := 0; i < s.config.Sync.ConcurrentVideos; i++ {
 		consumerWG.Add(1)
 		go func() {
 			defer consumerWG.Done()
@@ -172,7 +172,7 @@ type updateInfo struct {
 func transferVideos(s *Sync) error {
 	cleanTransfer := true
 
-	streamChan := make(chan updateInfo, s.ConcurrentVideos)
+	streamChan := make(chan updateInfo, s.config.Sync.ConcurrentVideos)
 	account, err := s.getDefaultAccount()
 	if err != nil {
 		return err
@@ -230,7 +230,7 @@ func transferVideos(s *Sync) error {
 	}()
 
 	consumerWG := &stop.Group{}
-	for i := 0; i < s.ConcurrentVideos; i++ {
+	for i := 0; i < s.config.Sync.ConcurrentVideos; i++ {
 		consumerWG.Add(1)
 		go func(worker int) {
 			defer consumerWG.Done()
@@ -267,13 +267,48 @@ func (s *Sync) streamUpdate(ui *updateInfo) error {
 		ui.videoStatus.IsTransferred = util.PtrToBool(len(result.Outputs) != 0)
 	}
 	log.Infof("TRANSFERRED %t", *ui.videoStatus.IsTransferred)
-	statusErr := s.APIConfig.MarkVideoStatus(*ui.videoStatus)
+	statusErr := s.Manager.reporter.Report(*ui.videoStatus)
 	if statusErr != nil {
 		return errors.Prefix(statusErr.Error(), updateError)
 	}
 	return errors.Err(updateError)
 }
 
+// transferCollections moves the collections published for the playlists of the channel to the address of the client,
+// they're signed by the channel and must follow it
+func transferCollections(s *Sync) error {
+	collections, err := s.Manager.jobStore.FetchSyncedCollections(s.YoutubeChannelID)
+	if err != nil {
+		return err
+	}
+	account, err := s.getDefaultAccount()
+	if err != nil {
+		return err
+	}
+	client := newCollectionsClient(s.instance.Address)
+	cleanTransfer := true
+	for _, collection := range collections {
+		if collection.ClaimID == "" || collection.Transferred {
+			continue
+		}
+		_, err := client.transfer(collection.ClaimID, s.clientPublishAddress, account)
+		if err != nil {
+			log.Errorf("failed to transfer collection %s: %s", collection.ClaimID, err.Error())
+			cleanTransfer = false
+			continue
+		}
+		collection.Transferred = true
+		err = s.Manager.jobStore.MarkCollectionStatus(s.YoutubeChannelID, collection)
+		if err != nil {
+			return err
+		}
+	}
+	if !cleanTransfer {
+		return errors.Err("A collection has failed to transfer for the channel...skipping channel transfer")
+	}
+	return nil
+}
+
 func transferChannel(s *Sync) error {
 	account, err := s.getDefaultAccount()
 	if err != nil {
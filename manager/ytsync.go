@@ -3,38 +3,34 @@ package manager
 import (
 	"fmt"
 	"io/ioutil"
-	"net/http"
+	"net/url"
 	"os"
-	"os/signal"
+	"regexp"
 	"runtime/debug"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
-	"syscall"
+	"text/template"
 	"time"
 
+	"github.com/lbryio/ytsync/downloader"
+	"github.com/lbryio/ytsync/errclass"
 	"github.com/lbryio/ytsync/ip_manager"
+	"github.com/lbryio/ytsync/media"
 	"github.com/lbryio/ytsync/namer"
 	"github.com/lbryio/ytsync/sdk"
 	"github.com/lbryio/ytsync/sources"
 	"github.com/lbryio/ytsync/thumbs"
 	logUtils "github.com/lbryio/ytsync/util"
+	"github.com/lbryio/ytsync/wallets"
 
 	"github.com/lbryio/lbry.go/v2/extras/errors"
 	"github.com/lbryio/lbry.go/v2/extras/jsonrpc"
 	"github.com/lbryio/lbry.go/v2/extras/stop"
 	"github.com/lbryio/lbry.go/v2/extras/util"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/awserr"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3"
-	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	log "github.com/sirupsen/logrus"
-	"google.golang.org/api/googleapi/transport"
-	"google.golang.org/api/youtube/v3"
 )
 
 const (
@@ -46,17 +42,8 @@ const (
 	maxReasonLength       = 500
 )
 
-type video interface {
-	Size() *int64
-	ID() string
-	IDAndNum() string
-	PlaylistPosition() int
-	PublishedAt() time.Time
-	Sync(*jsonrpc.Client, sources.SyncParams, *sdk.SyncedVideo, bool, *sync.RWMutex) (*sources.SyncSummary, error)
-}
-
 // sorting videos
-type byPublishedAt []video
+type byPublishedAt []sources.Video
 
 func (a byPublishedAt) Len() int           { return len(a) }
 func (a byPublishedAt) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
@@ -64,19 +51,13 @@ func (a byPublishedAt) Less(i, j int) bool { return a[i].PublishedAt().Before(a[
 
 // Sync stores the options that control how syncing happens
 type Sync struct {
-	APIConfig            *sdk.APIConfig
-	YoutubeChannelID     string
-	LbryChannelName      string
-	MaxTries             int
-	ConcurrentVideos     int
-	Refill               int
-	Manager              *SyncManager
-	LbrycrdString        string
-	AwsS3ID              string
-	AwsS3Secret          string
-	AwsS3Region          string
-	AwsS3Bucket          string
-	Fee                  *sdk.Fee
+	YoutubeChannelID string
+	LbryChannelName  string
+	Manager          *SyncManager
+	// Policy is the policy of the channel merged with the global settings, all of its fields are set
+	Policy               sdk.ChannelPolicy
+	config               *Config
+	instance             logUtils.Instance
 	daemon               *jsonrpc.Client
 	claimAddress         string
 	videoDirectory       string
@@ -86,11 +67,18 @@ type Sync struct {
 	lbryChannelID        string
 	namer                *namer.Namer
 	walletMux            *sync.RWMutex
-	queue                chan video
+	queue                chan sources.Video
+	source               sources.Source
+	downloader           downloader.Downloader
 	transferState        int
 	clientPublishAddress string
 	publicKey            string
 	defaultAccountID     string
+	removedVideosMux     *sync.Mutex
+	removedVideos        []removedVideo
+	descriptionTemplate  *template.Template
+	skipPatterns         []*regexp.Regexp
+	publishJournal       *publishJournal
 }
 
 func (s *Sync) AppendSyncedVideo(videoID string, published bool, failureReason string, claimName string, claimID string, metadataVersion int8, size int64) {
@@ -122,41 +110,16 @@ func (s *Sync) downloadWallet() error {
 	if err != nil {
 		return errors.Err(err)
 	}
-
-	creds := credentials.NewStaticCredentials(s.AwsS3ID, s.AwsS3Secret, "")
-	s3Session, err := session.NewSession(&aws.Config{Region: aws.String(s.AwsS3Region), Credentials: creds})
+	wallet, err := s.Manager.walletStore.Get(key)
 	if err != nil {
-		return errors.Prefix("error starting session: ", err)
+		return err
 	}
-	downloader := s3manager.NewDownloader(s3Session)
-	out, err := os.Create(defaultTempWalletDir)
-	if err != nil {
-		return errors.Prefix("error creating temp wallet: ", err)
+	if wallets.IsEncrypted(wallet) {
+		return errors.Err("the wallet is encrypted but no encryption key is set")
 	}
-	defer out.Close()
-
-	bytesWritten, err := downloader.Download(out, &s3.GetObjectInput{
-		Bucket: aws.String(s.AwsS3Bucket),
-		Key:    key,
-	})
+	err = ioutil.WriteFile(defaultTempWalletDir, wallet, 0600)
 	if err != nil {
-		// Casting to the awserr.Error type will allow you to inspect the error
-		// code returned by the service in code. The error code can be used
-		// to switch on context specific functionality. In this case a context
-		// specific error message is printed to the user based on the bucket
-		// and key existing.
-		//
-		// For information on other S3 API error codes see:
-		// http://docs.aws.amazon.com/AmazonS3/latest/API/ErrorResponses.html
-		if aerr, ok := err.(awserr.Error); ok {
-			code := aerr.Code()
-			if code == s3.ErrCodeNoSuchKey {
-				return errors.Err("wallet not on S3")
-			}
-		}
-		return err
-	} else if bytesWritten == 0 {
-		return errors.Err("zero bytes written")
+		return errors.Prefix("error creating temp wallet: ", err)
 	}
 
 	err = os.Rename(defaultTempWalletDir, defaultWalletDir)
@@ -167,59 +130,38 @@ func (s *Sync) downloadWallet() error {
 	return nil
 }
 
-func (s *Sync) getWalletPaths() (defaultWallet, tempWallet string, key *string, err error) {
-
-	defaultWallet = os.Getenv("HOME") + "/.lbryum/wallets/default_wallet"
-	tempWallet = os.Getenv("HOME") + "/.lbryum/wallets/tmp_wallet"
-	key = aws.String("/wallets/" + s.YoutubeChannelID)
+// walletKey is the key the wallet of the channel is stored under
+func (s *Sync) walletKey() string {
 	if logUtils.IsRegTest() {
-		defaultWallet = os.Getenv("HOME") + "/.lbryum_regtest/wallets/default_wallet"
-		tempWallet = os.Getenv("HOME") + "/.lbryum_regtest/wallets/tmp_wallet"
-		key = aws.String("/regtest/" + s.YoutubeChannelID)
+		return "/regtest/" + s.YoutubeChannelID
 	}
+	return "/wallets/" + s.YoutubeChannelID
+}
 
-	walletPath := os.Getenv("LBRYNET_WALLETS_DIR")
-	if walletPath != "" {
-		defaultWallet = walletPath + "/wallets/default_wallet"
-		tempWallet = walletPath + "/wallets/tmp_wallet"
-	}
+func (s *Sync) getWalletPaths() (defaultWallet, tempWallet string, key string, err error) {
+	walletsDir := s.instance.GetWalletsDir()
+	defaultWallet = walletsDir + "/wallets/default_wallet"
+	tempWallet = walletsDir + "/wallets/tmp_wallet"
+	key = s.walletKey()
 
 	if _, err := os.Stat(defaultWallet); !os.IsNotExist(err) {
-		return "", "", nil, errors.Err("default_wallet already exists")
+		return "", "", "", errors.Err("default_wallet already exists")
 	}
 	return
 }
 
 func (s *Sync) uploadWallet() error {
-	defaultWalletDir := logUtils.GetDefaultWalletPath()
-	key := aws.String("/wallets/" + s.YoutubeChannelID)
-	if logUtils.IsRegTest() {
-		key = aws.String("/regtest/" + s.YoutubeChannelID)
-	}
+	defaultWalletDir := s.instance.GetDefaultWalletPath()
 
 	if _, err := os.Stat(defaultWalletDir); os.IsNotExist(err) {
 		return errors.Err("default_wallet does not exist")
 	}
 
-	creds := credentials.NewStaticCredentials(s.AwsS3ID, s.AwsS3Secret, "")
-	s3Session, err := session.NewSession(&aws.Config{Region: aws.String(s.AwsS3Region), Credentials: creds})
-	if err != nil {
-		return err
-	}
-
-	uploader := s3manager.NewUploader(s3Session)
-
-	file, err := os.Open(defaultWalletDir)
+	wallet, err := ioutil.ReadFile(defaultWalletDir)
 	if err != nil {
-		return err
+		return errors.Err(err)
 	}
-	defer file.Close()
-
-	_, err = uploader.Upload(&s3manager.UploadInput{
-		Bucket: aws.String(s.AwsS3Bucket),
-		Key:    key,
-		Body:   file,
-	})
+	err = s.Manager.walletStore.Put(s.walletKey(), wallet)
 	if err != nil {
 		return err
 	}
@@ -228,7 +170,12 @@ func (s *Sync) uploadWallet() error {
 }
 
 func (s *Sync) setStatusSyncing() error {
-	syncedVideos, claimNames, err := s.Manager.apiConfig.SetChannelStatus(s.YoutubeChannelID, StatusSyncing, "", nil)
+	// the synced videos returned must include the statuses that are still pending
+	err := s.Manager.reporter.Flush()
+	if err != nil {
+		return errors.Prefix("could not report the pending video statuses", err)
+	}
+	syncedVideos, claimNames, err := s.Manager.jobStore.SetChannelStatus(s.YoutubeChannelID, StatusSyncing, "", nil)
 	if err != nil {
 		return err
 	}
@@ -239,15 +186,6 @@ func (s *Sync) setStatusSyncing() error {
 	return nil
 }
 
-func (s *Sync) setExceptions() {
-	if s.YoutubeChannelID == "UCwjQfNRW6sGYb__pd7d4nUg" { //@FreeTalkLive
-		s.Manager.maxVideoLength = 0.0 // skips max length checks
-		s.Manager.maxVideoSize = 0
-	}
-}
-
-var stopGroup = stop.New()
-
 func (s *Sync) FullCycle() (e error) {
 	if os.Getenv("HOME") == "" {
 		return errors.Err("no $HOME env var found")
@@ -255,30 +193,35 @@ func (s *Sync) FullCycle() (e error) {
 	if s.YoutubeChannelID == "" {
 		return errors.Err("channel ID not provided")
 	}
-
-	s.setExceptions()
+	err := s.skippedChannel()
+	if err != nil {
+		return err
+	}
 
 	s.syncedVideosMux = &sync.RWMutex{}
 	s.walletMux = &sync.RWMutex{}
-	s.grp = stopGroup
-	s.queue = make(chan video)
-	interruptChan := make(chan os.Signal, 1)
-	signal.Notify(interruptChan, os.Interrupt, syscall.SIGTERM)
-	defer signal.Stop(interruptChan)
-	go func() {
-		<-interruptChan
-		log.Println("Got interrupt signal, shutting down (if publishing, will shut down after current publish)")
-		s.grp.Stop()
-	}()
-	err := s.setStatusSyncing()
+	s.removedVideosMux = &sync.Mutex{}
+	s.grp = s.Manager.grp.Child()
+	s.queue = make(chan sources.Video)
+	err = s.setupSource()
+	if err != nil {
+		return err
+	}
+	err = s.setStatusSyncing()
 	if err != nil {
 		return err
 	}
 
 	defer s.setChannelTerminationStatus(&e)
 
+	s.publishJournal, err = openPublishJournal(s.config.JobStore.PublishJournals, s.YoutubeChannelID)
+	if err != nil {
+		return errors.Prefix("could not open the publish journal", err)
+	}
+	defer s.publishJournal.Close()
+
 	err = s.downloadWallet()
-	if err != nil && err.Error() != "wallet not on S3" {
+	if err != nil && !errors.Is(err, wallets.ErrNotFound) {
 		return errors.Prefix("failure in downloading wallet", err)
 	} else if err == nil {
 		log.Println("Continuing previous upload")
@@ -288,7 +231,7 @@ func (s *Sync) FullCycle() (e error) {
 
 	defer s.stopAndUploadWallet(&e)
 
-	s.videoDirectory, err = ioutil.TempDir(os.Getenv("TMP_DIR"), "ytsync")
+	s.videoDirectory, err = ioutil.TempDir(s.config.TmpDir, "ytsync")
 	if err != nil {
 		return errors.Wrap(err, 0)
 	}
@@ -298,14 +241,14 @@ func (s *Sync) FullCycle() (e error) {
 	}
 
 	defer deleteSyncFolder(s.videoDirectory)
-	log.Printf("Starting daemon")
-	err = logUtils.StartDaemon()
+	log.Printf("Starting daemon %s", s.instance)
+	err = s.instance.Start()
 	if err != nil {
 		return err
 	}
 
 	log.Infoln("Waiting for daemon to finish starting...")
-	s.daemon = jsonrpc.NewClient(os.Getenv("LBRYNET_ADDRESS"))
+	s.daemon = jsonrpc.NewClient(s.instance.Address)
 	s.daemon.SetRPCTimeout(40 * time.Minute)
 
 	err = s.waitForDaemonStart()
@@ -342,6 +285,10 @@ func (s *Sync) processTransfers() (e error) {
 	if err != nil {
 		return err
 	}
+	err = transferCollections(s)
+	if err != nil {
+		return err
+	}
 	err = transferChannel(s)
 	if err != nil {
 		return err
@@ -397,28 +344,28 @@ func (s *Sync) shouldTransfer() bool {
 func (s *Sync) setChannelTerminationStatus(e *error) {
 	var transferState *int
 
+	err := s.Manager.reporter.Flush()
+	if err != nil {
+		logUtils.SendErrorToSlack("(%s) %d video statuses could not be reported yet: %s", s.YoutubeChannelID, s.Manager.reporter.Pending(), err.Error())
+	}
+
 	if s.shouldTransfer() {
 		if *e == nil {
 			transferState = util.PtrToInt(TransferStateComplete)
 		}
 	}
 	if *e != nil {
-		//conditions for which a channel shouldn't be marked as failed
-		noFailConditions := []string{
-			"this youtube channel is being managed by another server",
-			"interrupted during daemon startup",
-		}
-		if util.SubstringInSlice((*e).Error(), noFailConditions) {
+		if errclass.Of(*e) == errclass.ChannelSkipped {
 			return
 		}
 		failureReason := (*e).Error()
-		_, _, err := s.Manager.apiConfig.SetChannelStatus(s.YoutubeChannelID, StatusFailed, failureReason, transferState)
+		_, _, err := s.Manager.jobStore.SetChannelStatus(s.YoutubeChannelID, StatusFailed, failureReason, transferState)
 		if err != nil {
 			msg := fmt.Sprintf("Failed setting failed state for channel %s", s.LbryChannelName)
 			*e = errors.Prefix(msg+err.Error(), *e)
 		}
 	} else if !s.IsInterrupted() {
-		_, _, err := s.Manager.apiConfig.SetChannelStatus(s.YoutubeChannelID, StatusSynced, "", transferState)
+		_, _, err := s.Manager.jobStore.SetChannelStatus(s.YoutubeChannelID, StatusSynced, "", transferState)
 		if err != nil {
 			*e = err
 		}
@@ -445,16 +392,51 @@ func (s *Sync) waitForDaemonStart() error {
 	}
 }
 
+// daemonCrashErrors are the errors the calls to a daemon that crashed fail with
+var daemonCrashErrors = []string{
+	"read: connection reset by peer",
+	"connect: connection refused",
+}
+
+// recoverFromDaemonCrash waits for a daemon run by ytsync to be restarted if err shows it crashed.
+// It returns true once the daemon is up again
+func (s *Sync) recoverFromDaemonCrash(err error) bool {
+	if logUtils.GetDaemonMode() != logUtils.DaemonModeProcess || !util.SubstringInSlice(err.Error(), daemonCrashErrors) {
+		return false
+	}
+	port := strconv.Itoa(jsonrpc.DefaultPort)
+	if u, parseErr := url.Parse(s.instance.Address); parseErr == nil && u.Port() != "" {
+		port = u.Port()
+	}
+	if !strings.Contains(err.Error(), ":"+port+":") {
+		// it's not the daemon that failed
+		return false
+	}
+	log.Warnf("daemon %s seems to have crashed, waiting for it to be restarted", s.instance)
+	beginTime := time.Now()
+	for time.Since(beginTime) < 10*time.Minute {
+		if s.IsInterrupted() || s.instance.HasFailed() {
+			return false
+		}
+		status, err := s.daemon.Status()
+		if err == nil && status.StartupStatus.Wallet && status.IsRunning {
+			return true
+		}
+		time.Sleep(5 * time.Second)
+	}
+	return false
+}
+
 func (s *Sync) stopAndUploadWallet(e *error) {
-	log.Printf("Stopping daemon")
-	shutdownErr := logUtils.StopDaemon()
+	log.Printf("Stopping daemon %s", s.instance)
+	shutdownErr := s.instance.Stop()
 	if shutdownErr != nil {
 		logShutdownError(shutdownErr)
 	} else {
 		// the cli will return long before the daemon effectively stops. we must observe the processes running
 		// before moving the wallet
 		waitTimeout := 8 * time.Minute
-		processDeathError := waitForDaemonProcess(waitTimeout)
+		processDeathError := waitForDaemonProcess(s.instance, waitTimeout)
 		if processDeathError != nil {
 			logShutdownError(processDeathError)
 		} else {
@@ -477,10 +459,10 @@ func logShutdownError(shutdownErr error) {
 
 var thumbnailHosts = []string{
 	"berk.ninja/thumbnails/",
-	thumbs.ThumbnailEndpoint,
+	thumbs.DefaultEndpoint,
 }
 
-func isYtsyncClaim(c jsonrpc.Claim, expectedChannelID string) bool {
+func isYtsyncClaim(c jsonrpc.Claim, expectedChannelIDs ...string) bool {
 	if !util.InSlice(c.Type, []string{"claim", "update"}) || c.Value.GetStream() == nil {
 		return false
 	}
@@ -491,10 +473,10 @@ func isYtsyncClaim(c jsonrpc.Claim, expectedChannelID string) bool {
 	if c.SigningChannel == nil {
 		return false
 	}
-	if c.SigningChannel.ClaimID != expectedChannelID {
+	if !util.InSlice(c.SigningChannel.ClaimID, expectedChannelIDs) {
 		return false
 	}
-	for _, th := range thumbnailHosts {
+	for _, th := range append(thumbnailHosts, thumbs.ThumbnailEndpoint) {
 		if strings.Contains(c.Value.GetThumbnail().GetUrl(), th) {
 			return true
 		}
@@ -507,11 +489,11 @@ func (s *Sync) fixDupes(claims []jsonrpc.Claim) (bool, error) {
 	abandonedClaims := false
 	videoIDs := make(map[string]jsonrpc.Claim)
 	for _, c := range claims {
-		if !isYtsyncClaim(c, s.lbryChannelID) {
+		if !isYtsyncClaim(c, s.channelIDs()...) {
 			continue
 		}
 		tn := c.Value.GetThumbnail().GetUrl()
-		videoID := tn[strings.LastIndex(tn, "/")+1:]
+		videoID := thumbs.VideoIDFromURL(tn)
 
 		cl, ok := videoIDs[videoID]
 		if !ok || cl.ClaimID == c.ClaimID {
@@ -554,7 +536,7 @@ type ytsyncClaim struct {
 func (s *Sync) mapFromClaims(claims []jsonrpc.Claim) map[string]ytsyncClaim {
 	videoIDMap := make(map[string]ytsyncClaim, len(claims))
 	for _, c := range claims {
-		if !isYtsyncClaim(c, s.lbryChannelID) {
+		if !isYtsyncClaim(c, s.channelIDs()...) {
 			continue
 		}
 		tn := c.Value.GetThumbnail().GetUrl()
@@ -576,8 +558,8 @@ func (s *Sync) mapFromClaims(claims []jsonrpc.Claim) map[string]ytsyncClaim {
 	return videoIDMap
 }
 
-//updateRemoteDB counts the amount of videos published so far and updates the remote db if some videos weren't marked as published
-//additionally it removes all entries in the database indicating that a video is published when it's actually not
+// updateRemoteDB counts the amount of videos published so far and updates the remote db if some videos weren't marked as published
+// additionally it removes all entries in the database indicating that a video is published when it's actually not
 func (s *Sync) updateRemoteDB(claims []jsonrpc.Claim, ownClaims []jsonrpc.Claim) (total, fixed, removed int, err error) {
 	allClaimsInfo := s.mapFromClaims(claims)
 	ownClaimsInfo := s.mapFromClaims(ownClaims)
@@ -592,7 +574,8 @@ func (s *Sync) updateRemoteDB(claims []jsonrpc.Claim, ownClaims []jsonrpc.Claim)
 		metadataDiffers := claimInDatabase && sv.MetadataVersion != int8(chainInfo.MetadataVersion)
 		claimIDDiffers := claimInDatabase && sv.ClaimID != chainInfo.ClaimID
 		claimNameDiffers := claimInDatabase && sv.ClaimName != chainInfo.ClaimName
-		claimMarkedUnpublished := claimInDatabase && !sv.Published
+		// claims of videos removed at source may be kept on chain on purpose
+		claimMarkedUnpublished := claimInDatabase && !sv.Published && sv.Status != VideoStatusRemovedAtSource
 		_, isOwnClaim := ownClaimsInfo[videoID]
 		tranferred := !isOwnClaim
 		transferStatusMismatch := sv.Transferred != tranferred
@@ -623,7 +606,7 @@ func (s *Sync) updateRemoteDB(claims []jsonrpc.Claim, ownClaims []jsonrpc.Claim)
 			}
 			fixed++
 			log.Debugf("updating %s in the database", videoID)
-			err = s.Manager.apiConfig.MarkVideoStatus(sdk.VideoStatus{
+			err = s.Manager.reporter.Report(sdk.VideoStatus{
 				ChannelID:       s.YoutubeChannelID,
 				VideoID:         videoID,
 				Status:          VideoStatusPublished,
@@ -674,7 +657,7 @@ func (s *Sync) updateRemoteDB(claims []jsonrpc.Claim, ownClaims []jsonrpc.Claim)
 	}
 	if s.Manager.SyncFlags.RemoveDBUnpublished && len(idsToRemove) > 0 {
 		log.Infof("removing: %s", strings.Join(idsToRemove, ","))
-		err := s.Manager.apiConfig.DeleteVideos(idsToRemove)
+		err := s.Manager.jobStore.DeleteVideos(idsToRemove)
 		if err != nil {
 			return count, fixed, len(idsToRemove), err
 		}
@@ -690,6 +673,14 @@ func (s *Sync) updateRemoteDB(claims []jsonrpc.Claim, ownClaims []jsonrpc.Claim)
 	return count, fixed, removed, nil
 }
 
+// channelIDs returns the IDs of the LBRY channels the videos of the youtube channel are published under
+func (s *Sync) channelIDs() []string {
+	if s.Policy.ShortsChannelID != "" {
+		return []string{s.lbryChannelID, s.Policy.ShortsChannelID}
+	}
+	return []string{s.lbryChannelID}
+}
+
 func (s *Sync) getClaims(defaultOnly bool) ([]jsonrpc.Claim, error) {
 	var account *string = nil
 	if defaultOnly {
@@ -705,7 +696,7 @@ func (s *Sync) getClaims(defaultOnly bool) ([]jsonrpc.Claim, error) {
 	}
 	items := make([]jsonrpc.Claim, 0, len(claims.Items))
 	for _, c := range claims.Items {
-		if c.SigningChannel != nil && c.SigningChannel.ClaimID == s.lbryChannelID {
+		if c.SigningChannel != nil && util.InSlice(c.SigningChannel.ClaimID, s.channelIDs()) {
 			items = append(items, c)
 		}
 	}
@@ -773,7 +764,11 @@ func (s *Sync) checkIntegrity() error {
 }
 
 func (s *Sync) doSync() error {
-	err := s.enableAddressReuse()
+	err := s.applyPolicy()
+	if err != nil {
+		return err
+	}
+	err = s.enableAddressReuse()
 	if err != nil {
 		return errors.Prefix("could not set address reuse policy", err)
 	}
@@ -786,6 +781,11 @@ func (s *Sync) doSync() error {
 		return errors.Prefix("Initial wallet setup failed! Manual Intervention is required.", err)
 	}
 
+	err = s.reconcilePublishJournal()
+	if err != nil {
+		return errors.Prefix("could not reconcile the publish journal", err)
+	}
+
 	err = s.checkIntegrity()
 	if err != nil {
 		return err
@@ -797,7 +797,7 @@ func (s *Sync) doSync() error {
 			return errors.Prefix("error getting channel cert", err)
 		}
 		if cert != nil {
-			err = s.APIConfig.SetChannelCert(string(*cert), s.lbryChannelID)
+			err = s.Manager.jobStore.SetChannelCert(string(*cert), s.lbryChannelID)
 			if err != nil {
 				return errors.Prefix("error setting channel cert", err)
 			}
@@ -808,7 +808,7 @@ func (s *Sync) doSync() error {
 		log.Println("Will stop publishing if an error is detected")
 	}
 
-	for i := 0; i < s.ConcurrentVideos; i++ {
+	for i := 0; i < s.config.Sync.ConcurrentVideos; i++ {
 		s.grp.Add(1)
 		go func(i int) {
 			defer s.grp.Done()
@@ -816,18 +816,21 @@ func (s *Sync) doSync() error {
 		}(i)
 	}
 
-	if s.LbryChannelName == "@UCBerkeley" {
-		err = errors.Err("UCB is not supported in this version of YTSYNC")
-	} else {
-		err = s.enqueueYoutubeVideos()
-	}
+	err = s.enqueueVideos()
 	close(s.queue)
 	s.grp.Wait()
+	s.reportRemovedVideos()
+	if err == nil && s.Manager.SyncFlags.SyncPlaylists && !s.IsInterrupted() {
+		collectionsErr := s.syncCollections()
+		if collectionsErr != nil {
+			logUtils.SendErrorToSlack("(%s) failed to sync playlists: %s", s.YoutubeChannelID, errors.FullTrace(collectionsErr))
+		}
+	}
 	return err
 }
 
 func (s *Sync) startWorker(workerNum int) {
-	var v video
+	var v sources.Video
 	var more bool
 
 	for {
@@ -858,55 +861,25 @@ func (s *Sync) startWorker(workerNum int) {
 			if err != nil {
 				logMsg := fmt.Sprintf("error processing video %s: %s", v.ID(), err.Error())
 				log.Errorln(logMsg)
-				if strings.Contains(strings.ToLower(err.Error()), "interrupted by user") {
+				class := errclass.Of(err)
+				if class == errclass.Interrupted {
 					return
 				}
-				fatalErrors := []string{
-					":5279: read: connection reset by peer",
-					"no space left on device",
-					"NotEnoughFunds",
-					"Cannot publish using channel",
-					"cannot concatenate 'str' and 'NoneType' objects",
-					"more than 90% of the space has been used.",
-					"Couldn't find private key for id",
-					"You already have a stream claim published under the name",
+				if s.recoverFromDaemonCrash(err) {
+					log.Println("Retrying")
+					tryCount--
+					continue
+				}
+				if s.instance.HasFailed() {
+					class = errclass.FatalChannel
 				}
-				if util.SubstringInSlice(err.Error(), fatalErrors) || s.Manager.SyncFlags.StopOnError {
+				if class.StopsChannel() || s.Manager.SyncFlags.StopOnError {
 					s.grp.Stop()
-				} else if s.MaxTries > 1 {
-					errorsNoRetry := []string{
-						"non 200 status code received",
-						"This video contains content from",
-						"dont know which claim to update",
-						"uploader has not made this video available in your country",
-						"download error: AccessDenied: Access Denied",
-						"Playback on other websites has been disabled by the video owner",
-						"Error in daemon: Cannot publish empty file",
-						"Error extracting sts from embedded url response",
-						"Unable to extract signature tokens",
-						"Client.Timeout exceeded while awaiting headers",
-						"the video is too big to sync, skipping for now",
-						"video is too long to process",
-						"no compatible format available for this video",
-						"Watch this video on YouTube.",
-						"have blocked it on copyright grounds",
-						"the video must be republished as we can't get the right size",
-						"HTTP Error 403",
-						"giving up after 0 fragment retries",
-						"Sorry about that",
-						"This video is not available",
-						"requested format not available",
-						"interrupted by user",
-						"Sign in to confirm your age",
-						"This video is unavailable",
-					}
-					if util.SubstringInSlice(err.Error(), errorsNoRetry) {
+				} else if s.config.Sync.MaxTries > 1 {
+					if class == errclass.NoRetry || class == errclass.PermanentVideo {
 						log.Println("This error should not be retried at all")
-					} else if tryCount < s.MaxTries {
-						if util.SubstringInSlice(err.Error(), []string{
-							"txn-mempool-conflict",
-							"too-long-mempool-chain",
-						}) {
+					} else if tryCount < s.config.Sync.MaxTries {
+						if class == errclass.RetryAfterBlock {
 							log.Println("waiting for a block before retrying")
 							err := s.waitForNewBlock()
 							if err != nil {
@@ -914,12 +887,7 @@ func (s *Sync) startWorker(workerNum int) {
 								logUtils.SendErrorToSlack("something went wrong while waiting for a block: %s", errors.FullTrace(err))
 								break
 							}
-						} else if util.SubstringInSlice(err.Error(), []string{
-							"Not enough funds to cover this transaction",
-							"failed: Not enough funds",
-							"Error in daemon: Insufficient funds, please deposit additional LBC",
-							"Missing inputs",
-						}) {
+						} else if class == errclass.RetryAfterRefill {
 							log.Println("checking funds and UTXOs before retrying...")
 							err := s.walletSetup()
 							if err != nil {
@@ -957,7 +925,7 @@ func (s *Sync) startWorker(workerNum int) {
 				} else {
 					s.AppendSyncedVideo(v.ID(), false, err.Error(), existingClaimName, existingClaimID, 0, existingClaimSize)
 				}
-				err = s.Manager.apiConfig.MarkVideoStatus(sdk.VideoStatus{
+				err = s.Manager.reporter.Report(sdk.VideoStatus{
 					ChannelID:     s.YoutubeChannelID,
 					VideoID:       v.ID(),
 					Status:        videoStatus,
@@ -975,95 +943,41 @@ func (s *Sync) startWorker(workerNum int) {
 	}
 }
 
-func (s *Sync) enqueueYoutubeVideos() error {
-	client := &http.Client{
-		Transport: &transport.APIKey{Key: s.APIConfig.YoutubeAPIKey},
-	}
-
-	service, err := youtube.New(client)
-	if err != nil {
-		return errors.Prefix("error creating YouTube service", err)
-	}
-
-	response, err := service.Channels.List("contentDetails").Id(s.YoutubeChannelID).Do()
-	if err != nil {
-		return errors.Prefix("error getting channels", err)
-	}
-
-	if len(response.Items) < 1 {
-		return errors.Err("youtube channel not found")
-	}
-
-	if response.Items[0].ContentDetails.RelatedPlaylists == nil {
-		return errors.Err("no related playlists")
-	}
-
-	playlistID := response.Items[0].ContentDetails.RelatedPlaylists.Uploads
-	if playlistID == "" {
-		return errors.Err("no channel playlist")
-	}
-
-	var videos []video
-	ipPool, err := ip_manager.GetIPPool(s.grp)
-	if err != nil {
-		return err
-	}
-
-	playlistMap := make(map[string]*youtube.PlaylistItemSnippet, 50)
-	nextPageToken := ""
-	for {
-		req := service.PlaylistItems.List("snippet").
-			PlaylistId(playlistID).
-			MaxResults(50).
-			PageToken(nextPageToken)
-
-		playlistResponse, err := req.Do()
-		if err != nil {
-			return errors.Prefix("error getting playlist items", err)
+// setupSource initializes the platform the videos of the channel are pulled from
+func (s *Sync) setupSource() error {
+	switch s.config.Sync.Source {
+	case sources.SourceLocal:
+		s.source = sources.NewLocalSource(s.config.Sync.LocalDir, s.Manager.GetS3AWSConfig(), s.grp)
+	case sources.SourceFeed:
+		if s.Policy.FeedURL == "" {
+			return errors.Err("no feed URL was set for this channel")
 		}
-
-		if len(playlistResponse.Items) < 1 {
-			// If there are 50+ videos in a playlist but less than 50 are actually returned by the API, youtube will still redirect
-			// clients to a next page. Such next page will however be empty. This logic prevents ytsync from failing.
-			youtubeIsLying := len(videos) > 0
-			if youtubeIsLying {
-				break
-			}
-			return errors.Err("playlist items not found")
-		}
-		videoIDs := make([]string, 50)
-		for i, item := range playlistResponse.Items {
-			// normally we'd send the video into the channel here, but youtube api doesn't have sorting
-			// so we have to get ALL the videos, then sort them, then send them in
-			playlistMap[item.Snippet.ResourceId.VideoId] = item.Snippet
-			videoIDs[i] = item.Snippet.ResourceId.VideoId
-		}
-		req2 := service.Videos.List("snippet,contentDetails,recordingDetails").Id(strings.Join(videoIDs[:], ","))
-
-		videosListResponse, err := req2.Do()
+		s.source = sources.NewFeedSource(s.Policy.FeedURL, s.Manager.GetS3AWSConfig(), s.grp)
+	default:
+		ipPool, err := ip_manager.GetIPPool(s.Manager.grp)
 		if err != nil {
-			return errors.Prefix("error getting videos info", err)
-		}
-		for _, item := range videosListResponse.Items {
-			videos = append(videos, sources.NewYoutubeVideo(s.videoDirectory, item, playlistMap[item.Id].Position, s.Manager.GetS3AWSConfig(), s.grp, ipPool))
+			return err
 		}
-
-		log.Infof("Got info for %d videos from youtube API", len(videos))
-
-		nextPageToken = playlistResponse.NextPageToken
-		if nextPageToken == "" || s.Manager.SyncFlags.QuickSync || len(videos) >= s.Manager.videosLimit {
-			break
+		s.downloader, err = downloader.New(s.config.Sync.Downloader)
+		if err != nil {
+			return err
 		}
+		s.source = sources.NewYoutubeSource(s.config.YoutubeAPIKey, s.Manager.GetS3AWSConfig(), s.grp, ipPool)
 	}
-	for k, v := range s.syncedVideos {
-		if !v.Published {
-			continue
-		}
-		_, ok := playlistMap[k]
-		if !ok {
-			videos = append(videos, sources.NewMockedVideo(s.videoDirectory, k, s.YoutubeChannelID, s.Manager.GetS3AWSConfig(), s.grp, ipPool))
-		}
+	return nil
+}
 
+func (s *Sync) enqueueVideos() error {
+	videos, err := s.source.ListVideos(s.YoutubeChannelID, sources.ListParams{
+		VideoDir:     s.videoDirectory,
+		SyncedVideos: s.syncedVideos,
+		VideosLimit:  *s.Policy.VideosLimit,
+		QuickSync:    s.Manager.SyncFlags.QuickSync,
+		// Shorts handled by a policy are a category of their own and get their own videos limit
+		SeparateShorts: s.Policy.ShortsPolicy != sources.ShortsPublish,
+	})
+	if err != nil {
+		return err
 	}
 	sort.Sort(byPublishedAt(videos))
 
@@ -1085,7 +999,7 @@ Enqueue:
 	return nil
 }
 
-func (s *Sync) processVideo(v video) (err error) {
+func (s *Sync) processVideo(v sources.Video) (err error) {
 	defer func() {
 		if p := recover(); p != nil {
 			logUtils.SendErrorToSlack("Video processing panic! %s", debug.Stack())
@@ -1108,38 +1022,55 @@ func (s *Sync) processVideo(v video) (err error) {
 	s.syncedVideosMux.RUnlock()
 	newMetadataVersion := int8(2)
 	alreadyPublished := ok && sv.Published
+	if !alreadyPublished && s.skippedByPolicy(v) {
+		return nil
+	}
 	videoRequiresUpgrade := ok && s.Manager.SyncFlags.UpgradeMetadata && sv.MetadataVersion < newMetadataVersion
+	fingerprint := ""
+	fv, isFingerprinted := v.(sources.FingerprintedVideo)
+	// the videos about to be published are fingerprinted once they are, fingerprinting looks the thumbnail up
+	if isFingerprinted && alreadyPublished && s.Manager.SyncFlags.DetectMetadataChanges {
+		fingerprint = fv.MetadataFingerprint()
+	}
+	if rv, isRemovable := v.(sources.RemovableVideo); isRemovable && rv.RemovedAtSource() {
+		if !alreadyPublished || sv.Status == VideoStatusRemovedAtSource {
+			return nil
+		}
+		return s.handleRemovedVideo(v.ID(), sv)
+	}
+	if alreadyPublished && !videoRequiresUpgrade && s.Manager.SyncFlags.DetectMetadataChanges && fingerprint != "" && sv.MetadataFingerprint == "" {
+		// the video was published before the changes were tracked, what's on the source now is the baseline
+		return s.recordFingerprint(v.ID(), sv, fingerprint)
+	}
+	metadataChanged := alreadyPublished && s.Manager.SyncFlags.DetectMetadataChanges && fingerprint != "" && fingerprint != sv.MetadataFingerprint
 
-	neverRetryFailures := []string{
-		"Error extracting sts from embedded url response",
-		"Unable to extract signature tokens",
-		"the video is too big to sync, skipping for now",
-		"video is too long to process",
-		"This video contains content from",
-		"no compatible format available for this video",
-		"Watch this video on YouTube.",
-		"have blocked it on copyright grounds",
-		"giving up after 0 fragment retries",
-		"Sign in to confirm your age",
-	}
-	if ok && !sv.Published && util.SubstringInSlice(sv.FailureReason, neverRetryFailures) {
+	// the shorts that were skipped are synced again if the shorts policy of the channel changed
+	if ok && !sv.Published && sv.Status != VideoStatusSkipped && errclass.OfMessage(sv.FailureReason) == errclass.PermanentVideo {
 		log.Println(v.ID() + " can't ever be published")
 		return nil
 	}
+	if ok && sv.Status == VideoStatusSkipped && s.Policy.ShortsPolicy == sources.ShortsSkip {
+		log.Println(v.ID() + " is a short, skipping")
+		return nil
+	}
 
-	if alreadyPublished && !videoRequiresUpgrade {
+	if metadataChanged {
+		log.Println(v.ID() + " changed on the source, updating its claim")
+	} else if alreadyPublished && !videoRequiresUpgrade {
 		log.Println(v.ID() + " already published")
 		return nil
-	}
-	if ok && sv.MetadataVersion >= newMetadataVersion {
+	} else if ok && sv.MetadataVersion >= newMetadataVersion {
 		log.Println(v.ID() + " upgraded to the new metadata")
 		return nil
 	}
 
-	if !videoRequiresUpgrade && v.PlaylistPosition() >= s.Manager.videosLimit {
+	if !videoRequiresUpgrade && !metadataChanged && v.PlaylistPosition() >= *s.Policy.VideosLimit {
 		log.Println(v.ID() + " is old: skipping")
 		return nil
 	}
+	if lv, isLive := v.(sources.LiveVideo); isLive && (lv.Upcoming() || lv.Live()) {
+		return s.deferVideo(v.ID(), sv, lv.Upcoming())
+	}
 	err = s.Manager.checkUsedSpace()
 	if err != nil {
 		return err
@@ -1149,39 +1080,143 @@ func (s *Sync) processVideo(v video) (err error) {
 		return err
 	}
 	sp := sources.SyncParams{
-		ClaimAddress:   s.claimAddress,
-		Amount:         publishAmount,
-		ChannelID:      s.lbryChannelID,
-		MaxVideoSize:   s.Manager.maxVideoSize,
-		Namer:          s.namer,
-		MaxVideoLength: s.Manager.maxVideoLength,
-		Fee:            s.Fee,
-		DefaultAccount: da,
+		ClaimAddress:        s.claimAddress,
+		Amount:              publishAmount,
+		ChannelID:           s.lbryChannelID,
+		MaxVideoSize:        *s.Policy.MaxVideoSize,
+		Namer:               s.namer,
+		MaxVideoLength:      *s.Policy.MaxVideoLength,
+		Fee:                 s.Policy.Fee,
+		DefaultAccount:      da,
+		Downloader:          s.downloader,
+		Qualities:           s.Policy.Qualities,
+		Tags:                s.Policy.Tags,
+		Subtitles:           s.config.subtitleOptions(),
+		MetadataChanged:     metadataChanged,
+		MaxVODLength:        s.config.Sync.MaxVODLength,
+		ShortsPolicy:        s.Policy.ShortsPolicy,
+		ShortsChannelID:     s.Policy.ShortsChannelID,
+		Chapters:            s.config.Sync.Chapters,
+		DescriptionTemplate: s.descriptionTemplate,
+	}
+	journal := s.publishJournal.forVideo(v.ID())
+	sp.Journal = journal
+	if s.Policy.License != "" {
+		sp.License = &sources.License{Name: s.Policy.License, URL: s.Policy.LicenseURL}
+	}
+	if profile, ok := media.Profiles[s.config.Sync.TranscodeProfile]; ok {
+		sp.TranscodeProfile = &profile
+	}
+
+	summary, err := v.Sync(s.daemon, sp, &sv, videoRequiresUpgrade || metadataChanged, s.walletMux)
+	if errors.Is(err, sources.ErrShortSkipped) {
+		return s.skipShort(v.ID(), sv)
 	}
-
-	summary, err := v.Sync(s.daemon, sp, &sv, videoRequiresUpgrade, s.walletMux)
 	if err != nil {
 		return err
 	}
 
+	if isFingerprinted && fingerprint == "" {
+		fingerprint = fv.MetadataFingerprint()
+	}
 	s.AppendSyncedVideo(v.ID(), true, "", summary.ClaimName, summary.ClaimID, newMetadataVersion, *v.Size())
-	err = s.Manager.apiConfig.MarkVideoStatus(sdk.VideoStatus{
-		ChannelID:       s.YoutubeChannelID,
-		VideoID:         v.ID(),
-		Status:          VideoStatusPublished,
-		ClaimID:         summary.ClaimID,
-		ClaimName:       summary.ClaimName,
-		Size:            v.Size(),
-		MetaDataVersion: LatestMetadataVersion,
-		IsTransferred:   util.PtrToBool(s.shouldTransfer()),
+	err = s.Manager.reporter.Report(sdk.VideoStatus{
+		ChannelID:           s.YoutubeChannelID,
+		VideoID:             v.ID(),
+		Status:              VideoStatusPublished,
+		ClaimID:             summary.ClaimID,
+		ClaimName:           summary.ClaimName,
+		Size:                v.Size(),
+		MetaDataVersion:     LatestMetadataVersion,
+		IsTransferred:       util.PtrToBool(s.shouldTransfer()),
+		CaptionLanguages:    summary.CaptionLanguages,
+		MetadataFingerprint: fingerprint,
 	})
 	if err != nil {
 		logUtils.SendErrorToSlack("Failed to mark video on the database: %s", errors.FullTrace(err))
+		return nil
+	}
+	if !s.Manager.reporter.Durable() {
+		// the claim stays in the publish journal until its status can't be lost anymore
+		err = s.Manager.reporter.Flush()
+		if err != nil {
+			log.Errorf("%s: could not report the status yet, its claim stays in the publish journal: %s", v.ID(), err.Error())
+			return nil
+		}
+	}
+	err = journal.done()
+	if err != nil {
+		log.Errorf("%s: could not update the publish journal: %s", v.ID(), err.Error())
 	}
 
 	return nil
 }
 
+// recordFingerprint stores the fingerprint of a published video without updating its claim
+func (s *Sync) recordFingerprint(videoID string, sv sdk.SyncedVideo, fingerprint string) error {
+	log.Println(videoID + " already published, recording its metadata fingerprint")
+	err := s.Manager.reporter.Report(sdk.VideoStatus{
+		ChannelID:           s.YoutubeChannelID,
+		VideoID:             videoID,
+		Status:              VideoStatusPublished,
+		ClaimID:             sv.ClaimID,
+		ClaimName:           sv.ClaimName,
+		Size:                &sv.Size,
+		MetaDataVersion:     uint(sv.MetadataVersion),
+		IsTransferred:       util.PtrToBool(sv.Transferred),
+		MetadataFingerprint: fingerprint,
+	})
+	if err != nil {
+		logUtils.SendErrorToSlack("Failed to mark video on the database: %s", errors.FullTrace(err))
+		return nil
+	}
+	s.syncedVideosMux.Lock()
+	sv.MetadataFingerprint = fingerprint
+	s.syncedVideos[videoID] = sv
+	s.syncedVideosMux.Unlock()
+	return nil
+}
+
+// deferVideo postpones a premiere or a live broadcast until a run where its recording can be downloaded
+func (s *Sync) deferVideo(videoID string, sv sdk.SyncedVideo, upcoming bool) error {
+	reason := "live broadcast in progress"
+	if upcoming {
+		reason = "upcoming premiere or live broadcast"
+	}
+	log.Printf("%s: %s, deferring it to a later run", videoID, reason)
+	if sv.Status == VideoStatusDeferred {
+		return nil
+	}
+	err := s.Manager.reporter.Report(sdk.VideoStatus{
+		ChannelID:     s.YoutubeChannelID,
+		VideoID:       videoID,
+		Status:        VideoStatusDeferred,
+		FailureReason: reason,
+	})
+	if err != nil {
+		logUtils.SendErrorToSlack("Failed to mark video on the database: %s", errors.FullTrace(err))
+	}
+	return nil
+}
+
+// skipShort records that the video is a Short the channel doesn't sync, so it's not downloaded again
+func (s *Sync) skipShort(videoID string, sv sdk.SyncedVideo) error {
+	log.Printf("%s is a short and the shorts of the channel are skipped", videoID)
+	if sv.Status == VideoStatusSkipped {
+		return nil
+	}
+	err := s.Manager.reporter.Report(sdk.VideoStatus{
+		ChannelID:     s.YoutubeChannelID,
+		VideoID:       videoID,
+		Status:        VideoStatusSkipped,
+		FailureReason: sources.ErrShortSkipped.Error(),
+	})
+	if err != nil {
+		logUtils.SendErrorToSlack("Failed to mark video on the database: %s", errors.FullTrace(err))
+	}
+	return nil
+}
+
 func (s *Sync) importPublicKey() error {
 	if s.publicKey != "" {
 		accountsResponse, err := s.daemon.AccountList(1, 50)
@@ -1206,7 +1241,7 @@ func (s *Sync) importPublicKey() error {
 	return nil
 }
 
-//TODO: fully implement this once I find a way to reliably get the abandoned supports amount
+// TODO: fully implement this once I find a way to reliably get the abandoned supports amount
 func (s *Sync) getUnsentSupports() (float64, error) {
 	defaultAccount, err := s.getDefaultAccount()
 	if err != nil {
@@ -1252,14 +1287,14 @@ func (s *Sync) getUnsentSupports() (float64, error) {
 }
 
 // waitForDaemonProcess observes the running processes and returns when the process is no longer running or when the timeout is up
-func waitForDaemonProcess(timeout time.Duration) error {
+func waitForDaemonProcess(instance logUtils.Instance, timeout time.Duration) error {
 	then := time.Now()
 	stopTime := then.Add(time.Duration(timeout * time.Second))
 	for !time.Now().After(stopTime) {
 		wait := 10 * time.Second
 		log.Println("the daemon is still running, waiting for it to exit")
 		time.Sleep(wait)
-		running, err := logUtils.IsLbrynetRunning()
+		running, err := instance.IsRunning()
 		if err != nil {
 			return errors.Err(err)
 		}
@@ -0,0 +1,47 @@
+package media
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+)
+
+// Chapter is a named section of a media file
+type Chapter struct {
+	// Start and End are expressed in seconds
+	Start float64
+	End   float64
+	Title string
+}
+
+var ffmetadataEscaper = strings.NewReplacer(`\`, `\\`, "=", `\=`, ";", `\;`, "#", `\#`, "\n", "\\\n")
+
+// ffmetadata formats chapters in the metadata format of ffmpeg
+func ffmetadata(chapters []Chapter) string {
+	var b strings.Builder
+	b.WriteString(";FFMETADATA1\n")
+	for _, c := range chapters {
+		b.WriteString("[CHAPTER]\nTIMEBASE=1/1000\n")
+		b.WriteString(fmt.Sprintf("START=%d\nEND=%d\n", int64(c.Start*1000), int64(c.End*1000)))
+		b.WriteString("title=" + ffmetadataEscaper.Replace(c.Title) + "\n")
+	}
+	return b.String()
+}
+
+// WriteChapters copies the file at input into output, replacing its chapters. Streams are not re-encoded. The copy is
+// aborted if stop is closed.
+func WriteChapters(input string, output string, chapters []Chapter, stop <-chan struct{}) error {
+	metadataPath := output + ".ffmetadata"
+	err := ioutil.WriteFile(metadataPath, []byte(ffmetadata(chapters)), 0644)
+	if err != nil {
+		return errors.Err(err)
+	}
+	defer os.Remove(metadataPath)
+
+	args := []string{"-hide_banner", "-loglevel", "error", "-y", "-i", input, "-i", metadataPath,
+		"-map", "0", "-map_metadata", "0", "-map_chapters", "1", "-c", "copy", "-movflags", "+faststart", output}
+	return runFFmpeg(args, output, stop)
+}
@@ -0,0 +1,16 @@
+package media
+
+import "testing"
+
+func TestFFMetadata(t *testing.T) {
+	chapters := []Chapter{
+		{Start: 0, End: 62.5, Title: "Intro"},
+		{Start: 62.5, End: 600, Title: "Q&A; part=1 #live"},
+	}
+	expected := ";FFMETADATA1\n" +
+		"[CHAPTER]\nTIMEBASE=1/1000\nSTART=0\nEND=62500\ntitle=Intro\n" +
+		"[CHAPTER]\nTIMEBASE=1/1000\nSTART=62500\nEND=600000\ntitle=Q&A\\; part\\=1 \\#live\n"
+	if metadata := ffmetadata(chapters); metadata != expected {
+		t.Errorf("unexpected metadata:\n%s", metadata)
+	}
+}
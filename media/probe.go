@@ -0,0 +1,163 @@
+package media
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	"github.com/lbryio/lbry.go/v2/extras/util"
+)
+
+var ErrNoVideo = errors.Base("no video stream found in the media file")
+var ErrNoAudio = errors.Base("no audio stream found in the media file")
+var ErrNoDuration = errors.Base("the media file has no duration")
+var ErrDurationMismatch = errors.Base("the duration of the media file is inconsistent")
+
+// Info holds the properties of a media file as reported by ffprobe
+type Info struct {
+	Width  uint
+	Height uint
+	// Duration is expressed in seconds
+	Duration   float64
+	VideoCodec string
+	AudioCodec string
+	// Bitrate is expressed in bits per second
+	Bitrate int64
+	// SubtitleLanguages are the languages of the text tracks of the file
+	SubtitleLanguages []string
+	// Container is the list of format names reported by ffprobe, e.g. "mov,mp4,m4a,3gp,3g2,mj2"
+	Container string
+	// videoDuration and audioDuration are the durations of the streams, if the container reports them
+	videoDuration float64
+	audioDuration float64
+}
+
+type probeStream struct {
+	CodecType   string `json:"codec_type"`
+	CodecName   string `json:"codec_name"`
+	Width       uint   `json:"width"`
+	Height      uint   `json:"height"`
+	Duration    string `json:"duration"`
+	Disposition struct {
+		AttachedPic int `json:"attached_pic"`
+	} `json:"disposition"`
+	Tags struct {
+		Language string `json:"language"`
+	} `json:"tags"`
+}
+
+type probeOutput struct {
+	Streams []probeStream `json:"streams"`
+	Format  struct {
+		FormatName string `json:"format_name"`
+		Duration   string `json:"duration"`
+		BitRate    string `json:"bit_rate"`
+	} `json:"format"`
+}
+
+// Probe runs ffprobe against the file at path
+func Probe(path string) (*Info, error) {
+	cmd := exec.Command("ffprobe", "-v", "error", "-print_format", "json", "-show_format", "-show_streams", path)
+	out, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, errors.Prefix("ffprobe failed", errors.Err(strings.TrimSpace(string(exitErr.Stderr))))
+		}
+		return nil, errors.Prefix("ffprobe failed", err)
+	}
+	return parseProbe(out)
+}
+
+func parseFloat(s string) float64 {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return f
+}
+
+func parseProbe(raw []byte) (*Info, error) {
+	var output probeOutput
+	err := json.Unmarshal(raw, &output)
+	if err != nil {
+		return nil, errors.Prefix("invalid ffprobe output", err)
+	}
+	info := &Info{
+		Duration:  parseFloat(output.Format.Duration),
+		Bitrate:   int64(parseFloat(output.Format.BitRate)),
+		Container: output.Format.FormatName,
+	}
+	for _, s := range output.Streams {
+		switch s.CodecType {
+		case "video":
+			// cover art embedded in audio files shows up as a video stream
+			if s.Disposition.AttachedPic == 1 || info.VideoCodec != "" {
+				continue
+			}
+			info.VideoCodec = s.CodecName
+			info.Width = s.Width
+			info.Height = s.Height
+			info.videoDuration = parseFloat(s.Duration)
+		case "audio":
+			if info.AudioCodec != "" {
+				continue
+			}
+			info.AudioCodec = s.CodecName
+			info.audioDuration = parseFloat(s.Duration)
+		case "subtitle":
+			// "und" is what muxers write when the language is unknown
+			if s.Tags.Language != "" && s.Tags.Language != "und" && !util.InSlice(s.Tags.Language, info.SubtitleLanguages) {
+				info.SubtitleLanguages = append(info.SubtitleLanguages, s.Tags.Language)
+			}
+		}
+	}
+	return info, nil
+}
+
+func (i *Info) HasVideo() bool {
+	return i.VideoCodec != ""
+}
+
+func (i *Info) HasAudio() bool {
+	return i.AudioCodec != ""
+}
+
+// Expectations describe what a media file must look like to be published
+type Expectations struct {
+	// Duration is the duration announced by the source in seconds, 0 skips the check
+	Duration     float64
+	RequireVideo bool
+	RequireAudio bool
+}
+
+// durationsMatch tolerates the small differences introduced by muxing and by sources rounding durations
+func durationsMatch(a, b float64) bool {
+	tolerance := math.Max(5, math.Max(a, b)*0.05)
+	return math.Abs(a-b) <= tolerance
+}
+
+// Check returns an error if the file doesn't satisfy the expectations or if its streams don't agree with each other
+func (i *Info) Check(e Expectations) error {
+	if e.RequireVideo && !i.HasVideo() {
+		return errors.Err(ErrNoVideo)
+	}
+	if e.RequireAudio && !i.HasAudio() {
+		return errors.Err(ErrNoAudio)
+	}
+	if i.Duration <= 0 {
+		return errors.Err(ErrNoDuration)
+	}
+	if e.Duration > 0 && !durationsMatch(i.Duration, e.Duration) {
+		return errors.Prefix(fmt.Sprintf("expected %.0fs, got %.0fs", e.Duration, i.Duration), ErrDurationMismatch)
+	}
+	for _, streamDuration := range []float64{i.videoDuration, i.audioDuration} {
+		if streamDuration > 0 && !durationsMatch(i.Duration, streamDuration) {
+			return errors.Prefix(fmt.Sprintf("a stream lasts %.0fs in a %.0fs file", streamDuration, i.Duration), ErrDurationMismatch)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package media
+
+import (
+	"testing"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+)
+
+const probeVideo = `{
+	"streams": [
+		{"codec_type": "video", "codec_name": "h264", "width": 1920, "height": 1080, "duration": "600.000000", "disposition": {"attached_pic": 0}},
+		{"codec_type": "audio", "codec_name": "aac", "duration": "599.980000", "disposition": {"attached_pic": 0}},
+		{"codec_type": "subtitle", "codec_name": "mov_text", "duration": "598.000000", "tags": {"language": "eng"}},
+		{"codec_type": "subtitle", "codec_name": "mov_text", "duration": "598.000000", "tags": {"language": "spa"}},
+		{"codec_type": "subtitle", "codec_name": "mov_text", "duration": "12.000000", "tags": {"language": "und"}}
+	],
+	"format": {"format_name": "mov,mp4,m4a,3gp,3g2,mj2", "duration": "600.010000", "bit_rate": "4500000"}
+}`
+
+const probeAudioWithCover = `{
+	"streams": [
+		{"codec_type": "audio", "codec_name": "mp3", "duration": "1800.000000", "disposition": {"attached_pic": 0}},
+		{"codec_type": "video", "codec_name": "mjpeg", "width": 600, "height": 600, "disposition": {"attached_pic": 1}}
+	],
+	"format": {"duration": "1800.000000", "bit_rate": "128000"}
+}`
+
+const probeBrokenMerge = `{
+	"streams": [
+		{"codec_type": "video", "codec_name": "h264", "width": 1280, "height": 720, "duration": "12.000000", "disposition": {"attached_pic": 0}},
+		{"codec_type": "audio", "codec_name": "aac", "duration": "600.000000", "disposition": {"attached_pic": 0}}
+	],
+	"format": {"duration": "600.000000", "bit_rate": "300000"}
+}`
+
+func TestParseProbe(t *testing.T) {
+	info, err := parseProbe([]byte(probeVideo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Width != 1920 || info.Height != 1080 || info.VideoCodec != "h264" || info.AudioCodec != "aac" || info.Bitrate != 4500000 {
+		t.Errorf("unexpected probe result: %+v", info)
+	}
+	if len(info.SubtitleLanguages) != 2 || info.SubtitleLanguages[0] != "eng" || info.SubtitleLanguages[1] != "spa" {
+		t.Errorf("unexpected subtitle languages: %v", info.SubtitleLanguages)
+	}
+
+	info, err = parseProbe([]byte(probeAudioWithCover))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.HasVideo() {
+		t.Errorf("cover art must not be treated as a video stream: %+v", info)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	cases := []struct {
+		probe        string
+		expectations Expectations
+		expected     error
+	}{
+		{probeVideo, Expectations{Duration: 600, RequireVideo: true, RequireAudio: true}, nil},
+		{probeVideo, Expectations{Duration: 1200, RequireVideo: true, RequireAudio: true}, ErrDurationMismatch},
+		{probeAudioWithCover, Expectations{RequireVideo: true, RequireAudio: true}, ErrNoVideo},
+		{probeAudioWithCover, Expectations{Duration: 1795, RequireAudio: true}, nil},
+		{probeBrokenMerge, Expectations{Duration: 600, RequireVideo: true, RequireAudio: true}, ErrDurationMismatch},
+	}
+	for i, c := range cases {
+		info, err := parseProbe([]byte(c.probe))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = info.Check(c.expectations)
+		if c.expected == nil && err != nil {
+			t.Errorf("case %d: unexpected error %s", i, err)
+		} else if c.expected != nil && !errors.Is(err, c.expected) {
+			t.Errorf("case %d: expected %s, got %v", i, c.expected, err)
+		}
+	}
+}
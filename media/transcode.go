@@ -0,0 +1,171 @@
+package media
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/lbryio/ytsync/errclass"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	"github.com/lbryio/lbry.go/v2/extras/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var ErrTranscodeInterrupted = errclass.New(errclass.Interrupted, "transcoding interrupted by user")
+
+// Profile describes the kind of files that play well in the LBRY apps and how to produce them
+type Profile struct {
+	Name string
+	// VideoCodecs and AudioCodecs are the codecs (as named by ffprobe) that are accepted as they are
+	VideoCodecs []string
+	AudioCodecs []string
+	// MaxBitrate is the highest overall bitrate accepted, in bits per second. 0 disables the check
+	MaxBitrate int64
+	// MaxHeight is the highest resolution accepted. 0 disables the check
+	MaxHeight uint
+	// VideoEncoder and AudioEncoder are the ffmpeg encoders used when a stream has to be re-encoded
+	VideoEncoder string
+	AudioEncoder string
+	// CRF and Preset tune the quality and the speed of the video encoder
+	CRF    int
+	Preset string
+	// VideoMaxrate caps the bitrate of re-encoded video streams, in bits per second
+	VideoMaxrate int64
+	// AudioBitrate is the bitrate of re-encoded audio streams, in bits per second
+	AudioBitrate int64
+}
+
+const (
+	ProfileH264     = "h264"
+	ProfileH264720p = "h264-720p"
+)
+
+// Profiles are the transcoding profiles that can be selected by name
+var Profiles = map[string]Profile{
+	ProfileH264: {
+		Name:         ProfileH264,
+		VideoCodecs:  []string{"h264"},
+		AudioCodecs:  []string{"aac"},
+		MaxBitrate:   8000000,
+		MaxHeight:    1080,
+		VideoEncoder: "libx264",
+		AudioEncoder: "aac",
+		CRF:          23,
+		Preset:       "medium",
+		VideoMaxrate: 6000000,
+		AudioBitrate: 160000,
+	},
+	ProfileH264720p: {
+		Name:         ProfileH264720p,
+		VideoCodecs:  []string{"h264"},
+		AudioCodecs:  []string{"aac"},
+		MaxBitrate:   4000000,
+		MaxHeight:    720,
+		VideoEncoder: "libx264",
+		AudioEncoder: "aac",
+		CRF:          23,
+		Preset:       "medium",
+		VideoMaxrate: 3000000,
+		AudioBitrate: 128000,
+	},
+}
+
+// ProfileNames returns the names of the available profiles
+func ProfileNames() []string {
+	return []string{ProfileH264, ProfileH264720p}
+}
+
+func (p Profile) videoFits(info *Info) bool {
+	if !util.InSlice(info.VideoCodec, p.VideoCodecs) {
+		return false
+	}
+	if p.MaxHeight > 0 && info.Height > p.MaxHeight {
+		return false
+	}
+	return p.MaxBitrate == 0 || info.Bitrate <= p.MaxBitrate
+}
+
+func (p Profile) audioFits(info *Info) bool {
+	return !info.HasAudio() || util.InSlice(info.AudioCodec, p.AudioCodecs)
+}
+
+func isMP4(info *Info) bool {
+	return util.InSlice("mp4", strings.Split(info.Container, ","))
+}
+
+// Needed returns true if the file described by info has to be transcoded to fit the profile.
+// Audio only files are left alone.
+func (p Profile) Needed(info *Info) bool {
+	if !info.HasVideo() {
+		return false
+	}
+	return !p.videoFits(info) || !p.audioFits(info) || !isMP4(info)
+}
+
+func (p Profile) args(input string, output string, info *Info) []string {
+	args := []string{"-hide_banner", "-loglevel", "error", "-y", "-i", input, "-map", "0:v:0", "-map", "0:a:0?", "-map", "0:s?"}
+	if p.videoFits(info) {
+		args = append(args, "-c:v", "copy")
+	} else {
+		args = append(args,
+			"-c:v", p.VideoEncoder,
+			"-preset", p.Preset,
+			"-crf", fmt.Sprintf("%d", p.CRF),
+			"-pix_fmt", "yuv420p",
+		)
+		if p.VideoMaxrate > 0 {
+			args = append(args,
+				"-maxrate", fmt.Sprintf("%d", p.VideoMaxrate),
+				"-bufsize", fmt.Sprintf("%d", 2*p.VideoMaxrate),
+			)
+		}
+		if p.MaxHeight > 0 && info.Height > p.MaxHeight {
+			args = append(args, "-vf", fmt.Sprintf("scale=-2:%d", p.MaxHeight))
+		}
+	}
+	if p.audioFits(info) {
+		args = append(args, "-c:a", "copy")
+	} else {
+		args = append(args, "-c:a", p.AudioEncoder, "-b:a", fmt.Sprintf("%d", p.AudioBitrate))
+	}
+	// mov_text is the only subtitle format mp4 supports
+	return append(args, "-c:s", "mov_text", "-movflags", "+faststart", output)
+}
+
+// Transcode converts the file at input into an mp4 file at output that fits the profile. Streams that already fit
+// the profile are copied without being re-encoded. The transcoding is aborted if stop is closed.
+func Transcode(input string, output string, info *Info, profile Profile, stop <-chan struct{}) error {
+	return runFFmpeg(profile.args(input, output, info), output, stop)
+}
+
+// runFFmpeg runs ffmpeg with args. output is removed if ffmpeg fails or is interrupted
+func runFFmpeg(args []string, output string, stop <-chan struct{}) error {
+	cmd := exec.Command("ffmpeg", args...)
+	log.Printf("Running command ffmpeg %s", strings.Join(args, " "))
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return errors.Err(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	select {
+	case <-stop:
+		_ = cmd.Process.Kill()
+		<-done
+		_ = os.Remove(output)
+		return errors.Err(ErrTranscodeInterrupted)
+	case err := <-done:
+		if err != nil {
+			_ = os.Remove(output)
+			return errors.Prefix("ffmpeg failed", errors.Err(strings.TrimSpace(stderr.String())))
+		}
+	}
+	return nil
+}
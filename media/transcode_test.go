@@ -0,0 +1,44 @@
+package media
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProfileNeeded(t *testing.T) {
+	profile := Profiles[ProfileH264]
+	mp4 := "mov,mp4,m4a,3gp,3g2,mj2"
+	cases := []struct {
+		info     Info
+		expected bool
+	}{
+		{Info{VideoCodec: "h264", AudioCodec: "aac", Height: 1080, Bitrate: 4000000, Container: mp4}, false},
+		{Info{VideoCodec: "vp9", AudioCodec: "opus", Height: 1080, Bitrate: 4000000, Container: "matroska,webm"}, true},
+		{Info{VideoCodec: "h264", AudioCodec: "aac", Height: 2160, Bitrate: 4000000, Container: mp4}, true},
+		{Info{VideoCodec: "h264", AudioCodec: "aac", Height: 1080, Bitrate: 20000000, Container: mp4}, true},
+		{Info{VideoCodec: "h264", AudioCodec: "aac", Height: 720, Bitrate: 2000000, Container: "matroska,webm"}, true},
+		{Info{AudioCodec: "mp3", Bitrate: 128000, Container: "mp3"}, false},
+	}
+	for i, c := range cases {
+		if profile.Needed(&c.info) != c.expected {
+			t.Errorf("case %d: expected %t for %+v", i, c.expected, c.info)
+		}
+	}
+}
+
+func TestProfileArgs(t *testing.T) {
+	profile := Profiles[ProfileH264]
+
+	args := strings.Join(profile.args("in.mkv", "out.mp4", &Info{VideoCodec: "h264", AudioCodec: "opus", Height: 1080, Bitrate: 4000000}), " ")
+	if !strings.Contains(args, "-c:v copy") || !strings.Contains(args, "-c:a aac") {
+		t.Errorf("only the audio should be re-encoded: %s", args)
+	}
+
+	args = strings.Join(profile.args("in.webm", "out.mp4", &Info{VideoCodec: "vp9", AudioCodec: "aac", Height: 2160, Bitrate: 20000000}), " ")
+	if !strings.Contains(args, "-c:v libx264") || !strings.Contains(args, "scale=-2:1080") || !strings.Contains(args, "-c:a copy") {
+		t.Errorf("only the video should be re-encoded and scaled down: %s", args)
+	}
+	if !strings.HasSuffix(args, "-movflags +faststart out.mp4") {
+		t.Errorf("the output must be a faststart mp4: %s", args)
+	}
+}
@@ -3,7 +3,6 @@ package sdk
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"net/url"
 	"regexp"
@@ -13,8 +12,7 @@ import (
 
 	"github.com/lbryio/lbry.go/v2/extras/errors"
 	"github.com/lbryio/lbry.go/v2/extras/null"
-
-	"github.com/lbryio/ytsync/util"
+	"github.com/lbryio/lbry.go/v2/extras/stop"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -23,11 +21,17 @@ const (
 	MaxReasonLength = 490
 )
 
+// APIConfig is the JobStore backed by the internal LBRY web API
 type APIConfig struct {
-	YoutubeAPIKey string
-	ApiURL        string
-	ApiToken      string
-	HostName      string
+	ApiURL   string
+	ApiToken string
+	HostName string
+	// Client defaults to a client with a timeout of a few minutes
+	Client *http.Client
+	// Retry defaults to DefaultRetryPolicy
+	Retry RetryPolicy
+	// Stop interrupts the calls in progress once stopped, it can be nil
+	Stop *stop.Group
 }
 
 type SyncProperties struct {
@@ -37,15 +41,17 @@ type SyncProperties struct {
 }
 
 type SyncFlags struct {
-	StopOnError             bool
-	TakeOverExistingChannel bool
-	SkipSpaceCheck          bool
-	SyncUpdate              bool
-	SingleRun               bool
-	RemoveDBUnpublished     bool
-	UpgradeMetadata         bool
-	DisableTransfers        bool
-	QuickSync               bool
+	StopOnError             bool `yaml:"stop_on_error"`
+	TakeOverExistingChannel bool `yaml:"takeover_existing_channel"`
+	SkipSpaceCheck          bool `yaml:"skip_space_check"`
+	SyncUpdate              bool `yaml:"update"`
+	SingleRun               bool `yaml:"run_once"`
+	RemoveDBUnpublished     bool `yaml:"remove_db_unpublished"`
+	UpgradeMetadata         bool `yaml:"upgrade_metadata"`
+	DisableTransfers        bool `yaml:"no_transfers"`
+	QuickSync               bool `yaml:"quick"`
+	SyncPlaylists           bool `yaml:"sync_playlists"`
+	DetectMetadataChanges   bool `yaml:"detect_metadata_changes"`
 }
 
 type Fee struct {
@@ -62,6 +68,8 @@ type YoutubeChannel struct {
 	TransferState      int    `json:"transfer_state"`
 	PublishAddress     string `json:"publish_address"`
 	PublicKey          string `json:"public_key"`
+	// Policy overrides the global settings for this channel, see ChannelPolicy
+	Policy *ChannelPolicy `json:"policy"`
 }
 
 func (a *APIConfig) FetchChannels(status string, cp *SyncProperties) ([]YoutubeChannel, error) {
@@ -71,7 +79,7 @@ func (a *APIConfig) FetchChannels(status string, cp *SyncProperties) ([]YoutubeC
 		Data    []YoutubeChannel `json:"data"`
 	}
 	endpoint := a.ApiURL + "/yt/jobs"
-	res, err := http.PostForm(endpoint, url.Values{
+	body, err := a.post(endpoint, url.Values{
 		"auth_token":  {a.ApiToken},
 		"sync_status": {status},
 		"min_videos":  {strconv.Itoa(1)},
@@ -81,15 +89,7 @@ func (a *APIConfig) FetchChannels(status string, cp *SyncProperties) ([]YoutubeC
 		"channel_id":  {cp.YoutubeChannelID},
 	})
 	if err != nil {
-		return nil, errors.Err(err)
-	}
-	defer res.Body.Close()
-	body, _ := ioutil.ReadAll(res.Body)
-	if res.StatusCode != http.StatusOK {
-		util.SendErrorToSlack("Error %d while trying to call %s. Waiting to retry", res.StatusCode, endpoint)
-		log.Debugln(string(body))
-		time.Sleep(30 * time.Second)
-		return a.FetchChannels(status, cp)
+		return nil, err
 	}
 	var response apiJobsResponse
 	err = json.Unmarshal(body, &response)
@@ -112,6 +112,9 @@ type SyncedVideo struct {
 	Size            int64  `json:"size"`
 	MetadataVersion int8   `json:"metadata_version"`
 	Transferred     bool   `json:"transferred"`
+	Status          string `json:"status"`
+	// MetadataFingerprint identifies the source metadata the claim was last published or updated with
+	MetadataFingerprint string `json:"metadata_fingerprint"`
 }
 
 func sanitizeFailureReason(s *string) {
@@ -133,21 +136,13 @@ func (a *APIConfig) SetChannelCert(certHex string, channelID string) error {
 
 	endpoint := a.ApiURL + "/yt/channel_cert"
 
-	res, err := http.PostForm(endpoint, url.Values{
+	body, err := a.post(endpoint, url.Values{
 		"channel_claim_id": {channelID},
 		"channel_cert":     {certHex},
 		"auth_token":       {a.ApiToken},
 	})
 	if err != nil {
-		return errors.Err(err)
-	}
-	defer res.Body.Close()
-	body, _ := ioutil.ReadAll(res.Body)
-	if res.StatusCode != http.StatusOK {
-		util.SendErrorToSlack("Error %d while trying to call %s. Waiting to retry", res.StatusCode, endpoint)
-		log.Debugln(body)
-		time.Sleep(30 * time.Second)
-		return a.SetChannelCert(certHex, channelID)
+		return err
 	}
 	var response apiSetChannelCertResponse
 	err = json.Unmarshal(body, &response)
@@ -180,17 +175,9 @@ func (a *APIConfig) SetChannelStatus(channelID string, status string, failureRea
 	if transferState != nil {
 		params.Add("transfer_state", strconv.Itoa(*transferState))
 	}
-	res, err := http.PostForm(endpoint, params)
+	body, err := a.post(endpoint, params)
 	if err != nil {
-		return nil, nil, errors.Err(err)
-	}
-	defer res.Body.Close()
-	body, _ := ioutil.ReadAll(res.Body)
-	if res.StatusCode >= http.StatusInternalServerError {
-		util.SendErrorToSlack("Error %d while trying to call %s. Waiting to retry", res.StatusCode, endpoint)
-		log.Debugln(string(body))
-		time.Sleep(30 * time.Second)
-		return a.SetChannelStatus(channelID, status, failureReason, transferState)
+		return nil, nil, err
 	}
 	var response apiChannelStatusResponse
 	err = json.Unmarshal(body, &response)
@@ -211,7 +198,7 @@ func (a *APIConfig) SetChannelStatus(channelID string, status string, failureRea
 		}
 		return svs, claimNames, nil
 	}
-	return nil, nil, errors.Err("invalid API response. Status code: %d", res.StatusCode)
+	return nil, nil, errors.Err("invalid API response: %s", string(body))
 }
 
 func (a *APIConfig) SetChannelClaimID(channelID string, channelClaimID string) error {
@@ -221,21 +208,13 @@ func (a *APIConfig) SetChannelClaimID(channelID string, channelClaimID string) e
 		Data    string      `json:"data"`
 	}
 	endpoint := a.ApiURL + "/yt/set_channel_claim_id"
-	res, err := http.PostForm(endpoint, url.Values{
+	body, err := a.post(endpoint, url.Values{
 		"channel_id":       {channelID},
 		"auth_token":       {a.ApiToken},
 		"channel_claim_id": {channelClaimID},
 	})
 	if err != nil {
-		return errors.Err(err)
-	}
-	defer res.Body.Close()
-	body, _ := ioutil.ReadAll(res.Body)
-	if res.StatusCode != http.StatusOK {
-		util.SendErrorToSlack("Error %d while trying to call %s. Waiting to retry", res.StatusCode, endpoint)
-		log.Debugln(string(body))
-		time.Sleep(30 * time.Second)
-		return a.SetChannelClaimID(channelID, channelClaimID)
+		return err
 	}
 	var response apiChannelStatusResponse
 	err = json.Unmarshal(body, &response)
@@ -252,9 +231,10 @@ func (a *APIConfig) SetChannelClaimID(channelID string, channelClaimID string) e
 }
 
 const (
-	VideoStatusPublished     = "published"
-	VideoStatusUpgradeFailed = "upgradefailed"
-	VideoStatusFailed        = "failed"
+	VideoStatusPublished       = "published"
+	VideoStatusUpgradeFailed   = "upgradefailed"
+	VideoStatusFailed          = "failed"
+	VideoStatusRemovedAtSource = "removedatsource"
 )
 
 func (a *APIConfig) DeleteVideos(videos []string) error {
@@ -264,17 +244,9 @@ func (a *APIConfig) DeleteVideos(videos []string) error {
 		"video_ids":  {videoIDs},
 		"auth_token": {a.ApiToken},
 	}
-	res, err := http.PostForm(endpoint, vals)
+	body, err := a.post(endpoint, vals)
 	if err != nil {
-		return errors.Err(err)
-	}
-	defer res.Body.Close()
-	body, _ := ioutil.ReadAll(res.Body)
-	if res.StatusCode != http.StatusOK {
-		util.SendErrorToSlack("Error %d while trying to call %s. Waiting to retry", res.StatusCode, endpoint)
-		log.Debugln(string(body))
-		time.Sleep(30 * time.Second)
-		return a.DeleteVideos(videos)
+		return err
 	}
 	var response struct {
 		Success bool        `json:"success"`
@@ -292,62 +264,222 @@ func (a *APIConfig) DeleteVideos(videos []string) error {
 	if !response.Data.IsNull() && response.Data.String == "ok" {
 		return nil
 	}
-	return errors.Err("invalid API response. Status code: %d", res.StatusCode)
+	return errors.Err("invalid API response: %s", string(body))
 }
 
 type VideoStatus struct {
-	ChannelID       string
-	VideoID         string
-	Status          string
-	ClaimID         string
-	ClaimName       string
-	FailureReason   string
-	Size            *int64
-	MetaDataVersion uint
-	IsTransferred   *bool
+	ChannelID       string `json:"channel_id"`
+	VideoID         string `json:"video_id"`
+	Status          string `json:"status"`
+	ClaimID         string `json:"claim_id,omitempty"`
+	ClaimName       string `json:"claim_name,omitempty"`
+	FailureReason   string `json:"failure_reason,omitempty"`
+	Size            *int64 `json:"size,omitempty"`
+	MetaDataVersion uint   `json:"metadata_version,omitempty"`
+	IsTransferred   *bool  `json:"transferred,omitempty"`
+	// CaptionLanguages are the languages of the captions published along with the video
+	CaptionLanguages []string `json:"caption_languages,omitempty"`
+	// MetadataFingerprint identifies the source metadata the claim was published or updated with
+	MetadataFingerprint string `json:"metadata_fingerprint,omitempty"`
+	// PublishedAt is when the status was reported, as a unix timestamp. It defaults to the time it's sent at
+	PublishedAt int64 `json:"published_at,omitempty"`
+}
+
+// hasClaim returns true if the status is one of a video that has a claim, which must then be provided
+func (s VideoStatus) hasClaim() bool {
+	return s.Status == VideoStatusPublished || s.Status == VideoStatusUpgradeFailed || s.Status == VideoStatusRemovedAtSource
+}
+
+func (s VideoStatus) validate() error {
+	if s.hasClaim() && (s.ClaimID == "" || s.ClaimName == "") {
+		return errors.Err("claimID (%s) or claimName (%s) missing", s.ClaimID, s.ClaimName)
+	}
+	return nil
+}
+
+// values returns the fields of the status as the API expects them
+func (s VideoStatus) values() url.Values {
+	vals := url.Values{
+		"youtube_channel_id": {s.ChannelID},
+		"video_id":           {s.VideoID},
+		"status":             {s.Status},
+	}
+	if s.hasClaim() {
+		publishedAt := s.PublishedAt
+		if publishedAt == 0 {
+			publishedAt = time.Now().Unix()
+		}
+		vals.Add("published_at", strconv.FormatInt(publishedAt, 10))
+		vals.Add("claim_id", s.ClaimID)
+		vals.Add("claim_name", s.ClaimName)
+		if s.MetaDataVersion > 0 {
+			vals.Add("metadata_version", fmt.Sprintf("%d", s.MetaDataVersion))
+		}
+		if s.Size != nil {
+			vals.Add("size", strconv.FormatInt(*s.Size, 10))
+		}
+		if len(s.CaptionLanguages) > 0 {
+			vals.Add("caption_languages", strings.Join(s.CaptionLanguages, ","))
+		}
+		if s.MetadataFingerprint != "" {
+			vals.Add("metadata_fingerprint", s.MetadataFingerprint)
+		}
+	}
+	if s.FailureReason != "" {
+		vals.Add("failure_reason", s.FailureReason)
+	}
+	if s.IsTransferred != nil {
+		vals.Add("transferred", strconv.FormatBool(*s.IsTransferred))
+	}
+	return vals
 }
 
 func (a *APIConfig) MarkVideoStatus(status VideoStatus) error {
 	endpoint := a.ApiURL + "/yt/video_status"
 
 	sanitizeFailureReason(&status.FailureReason)
-	vals := url.Values{
-		"youtube_channel_id": {status.ChannelID},
-		"video_id":           {status.VideoID},
-		"status":             {status.Status},
-		"auth_token":         {a.ApiToken},
-	}
-	if status.Status == VideoStatusPublished || status.Status == VideoStatusUpgradeFailed {
-		if status.ClaimID == "" || status.ClaimName == "" {
-			return errors.Err("claimID (%s) or claimName (%s) missing", status.ClaimID, status.ClaimName)
+	err := status.validate()
+	if err != nil {
+		return err
+	}
+	vals := status.values()
+	vals.Set("auth_token", a.ApiToken)
+	body, err := a.post(endpoint, vals)
+	if err != nil {
+		return err
+	}
+	var response struct {
+		Success bool        `json:"success"`
+		Error   null.String `json:"error"`
+		Data    null.String `json:"data"`
+	}
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return err
+	}
+	if !response.Error.IsNull() {
+		return errors.Err(response.Error.String)
+	}
+	if !response.Data.IsNull() && response.Data.String == "ok" {
+		return nil
+	}
+	return errors.Err("invalid API response: %s", string(body))
+}
+
+// MarkVideoStatuses reports the statuses in a single call. If the API doesn't support it yet, they're reported one
+// by one
+func (a *APIConfig) MarkVideoStatuses(statuses []VideoStatus) error {
+	endpoint := a.ApiURL + "/yt/video_statuses"
+
+	batch := make([]map[string]string, 0, len(statuses))
+	for _, status := range statuses {
+		sanitizeFailureReason(&status.FailureReason)
+		err := status.validate()
+		if err != nil {
+			return err
 		}
-		vals.Add("published_at", strconv.FormatInt(time.Now().Unix(), 10))
-		vals.Add("claim_id", status.ClaimID)
-		vals.Add("claim_name", status.ClaimName)
-		if status.MetaDataVersion > 0 {
-			vals.Add("metadata_version", fmt.Sprintf("%d", status.MetaDataVersion))
+		fields := make(map[string]string)
+		for k, v := range status.values() {
+			fields[k] = v[0]
 		}
-		if status.Size != nil {
-			vals.Add("size", strconv.FormatInt(*status.Size, 10))
+		batch = append(batch, fields)
+	}
+	encoded, err := json.Marshal(batch)
+	if err != nil {
+		return errors.Err(err)
+	}
+	body, err := a.post(endpoint, url.Values{
+		"statuses":   {string(encoded)},
+		"auth_token": {a.ApiToken},
+	})
+	if hasStatus(err, http.StatusNotFound) {
+		for _, status := range statuses {
+			err = a.MarkVideoStatus(status)
+			if err != nil {
+				return err
+			}
 		}
+		return nil
 	}
-	if status.FailureReason != "" {
-		vals.Add("failure_reason", status.FailureReason)
+	if err != nil {
+		return err
 	}
-	if status.IsTransferred != nil {
-		vals.Add("transferred", strconv.FormatBool(*status.IsTransferred))
+	var response struct {
+		Success bool        `json:"success"`
+		Error   null.String `json:"error"`
+		Data    null.String `json:"data"`
 	}
-	res, err := http.PostForm(endpoint, vals)
+	err = json.Unmarshal(body, &response)
 	if err != nil {
 		return errors.Err(err)
 	}
-	defer res.Body.Close()
-	body, _ := ioutil.ReadAll(res.Body)
-	if res.StatusCode != http.StatusOK {
-		util.SendErrorToSlack("Error %d while trying to call %s. Waiting to retry", res.StatusCode, endpoint)
-		log.Debugln(string(body))
-		time.Sleep(30 * time.Second)
-		return a.MarkVideoStatus(status)
+	if !response.Error.IsNull() {
+		return errors.Err(response.Error.String)
+	}
+	if !response.Data.IsNull() && response.Data.String == "ok" {
+		return nil
+	}
+	return errors.Err("invalid API response: %s", string(body))
+}
+
+// SyncedCollection is the state of a playlist that was published as a collection
+type SyncedCollection struct {
+	PlaylistID  string `json:"playlist_id"`
+	ClaimID     string `json:"claim_id"`
+	ClaimName   string `json:"claim_name"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	// ThumbnailURL is the thumbnail of the playlist on the source, before it's mirrored
+	ThumbnailURL string   `json:"thumbnail_url"`
+	Items        []string `json:"items"`
+	Transferred  bool     `json:"transferred"`
+}
+
+func (a *APIConfig) FetchSyncedCollections(channelID string) (map[string]SyncedCollection, error) {
+	type apiCollectionsResponse struct {
+		Success bool               `json:"success"`
+		Error   null.String        `json:"error"`
+		Data    []SyncedCollection `json:"data"`
+	}
+	endpoint := a.ApiURL + "/yt/collections"
+	body, err := a.post(endpoint, url.Values{
+		"channel_id": {channelID},
+		"auth_token": {a.ApiToken},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var response apiCollectionsResponse
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	if !response.Error.IsNull() {
+		return nil, errors.Err(response.Error.String)
+	}
+	collections := make(map[string]SyncedCollection, len(response.Data))
+	for _, c := range response.Data {
+		collections[c.PlaylistID] = c
+	}
+	return collections, nil
+}
+
+func (a *APIConfig) MarkCollectionStatus(channelID string, collection SyncedCollection) error {
+	endpoint := a.ApiURL + "/yt/collection_status"
+	body, err := a.post(endpoint, url.Values{
+		"channel_id":    {channelID},
+		"playlist_id":   {collection.PlaylistID},
+		"claim_id":      {collection.ClaimID},
+		"claim_name":    {collection.ClaimName},
+		"title":         {collection.Title},
+		"description":   {collection.Description},
+		"thumbnail_url": {collection.ThumbnailURL},
+		"items":         {strings.Join(collection.Items, ",")},
+		"transferred":   {strconv.FormatBool(collection.Transferred)},
+		"auth_token":    {a.ApiToken},
+	})
+	if err != nil {
+		return err
 	}
 	var response struct {
 		Success bool        `json:"success"`
@@ -356,7 +488,7 @@ func (a *APIConfig) MarkVideoStatus(status VideoStatus) error {
 	}
 	err = json.Unmarshal(body, &response)
 	if err != nil {
-		return err
+		return errors.Err(err)
 	}
 	if !response.Error.IsNull() {
 		return errors.Err(response.Error.String)
@@ -364,5 +496,5 @@ func (a *APIConfig) MarkVideoStatus(status VideoStatus) error {
 	if !response.Data.IsNull() && response.Data.String == "ok" {
 		return nil
 	}
-	return errors.Err("invalid API response. Status code: %d", res.StatusCode)
+	return errors.Err("invalid API response: %s", string(body))
 }
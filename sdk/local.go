@@ -0,0 +1,361 @@
+package sdk
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	channelStatusQueued  = "queued"
+	channelStatusSyncing = "syncing"
+)
+
+var (
+	channelsBucket    = []byte("channels")
+	videosBucket      = []byte("videos")
+	collectionsBucket = []byte("collections")
+)
+
+// LocalStore is the JobStore kept in an embedded database, so ytsync can run without the internal LBRY web API.
+// Channels are added with AddChannel
+type LocalStore struct {
+	db       *bolt.DB
+	hostName string
+}
+
+// localChannel is a channel as it's stored, along with its sync state
+type localChannel struct {
+	YoutubeChannel
+	Status        string `json:"status"`
+	FailureReason string `json:"failure_reason"`
+	SyncServer    string `json:"sync_server"`
+	ChannelCert   string `json:"channel_cert"`
+	AddedAt       int64  `json:"added_at"`
+	UpdatedAt     int64  `json:"updated_at"`
+}
+
+// OpenLocalStore opens the database at path, creating it if needed. hostName identifies this server as the one
+// syncing the channels, like it does with the API
+func OpenLocalStore(path string, hostName string) (*LocalStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, errors.Prefix("could not open the job store "+path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, b := range [][]byte{channelsBucket, videosBucket, collectionsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(b); err != nil {
+				return errors.Err(err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &LocalStore{db: db, hostName: hostName}, nil
+}
+
+func (l *LocalStore) Close() error {
+	return errors.Err(l.db.Close())
+}
+
+// AddChannel queues a channel for syncing. Channels that are already known are left alone
+func (l *LocalStore) AddChannel(channel YoutubeChannel) error {
+	if channel.ChannelId == "" {
+		return errors.Err("a channel ID is required")
+	}
+	return l.db.Update(func(tx *bolt.Tx) error {
+		existing, err := getChannel(tx, channel.ChannelId)
+		if err != nil || existing != nil {
+			return err
+		}
+		now := time.Now().Unix()
+		return putChannel(tx, &localChannel{
+			YoutubeChannel: channel,
+			Status:         channelStatusQueued,
+			AddedAt:        now,
+			UpdatedAt:      now,
+		})
+	})
+}
+
+func getChannel(tx *bolt.Tx, channelID string) (*localChannel, error) {
+	value := tx.Bucket(channelsBucket).Get([]byte(channelID))
+	if value == nil {
+		return nil, nil
+	}
+	var c localChannel
+	err := json.Unmarshal(value, &c)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	return &c, nil
+}
+
+func putChannel(tx *bolt.Tx, c *localChannel) error {
+	value, err := json.Marshal(c)
+	if err != nil {
+		return errors.Err(err)
+	}
+	return errors.Err(tx.Bucket(channelsBucket).Put([]byte(c.ChannelId), value))
+}
+
+// updateChannel applies update to the stored channel
+func (l *LocalStore) updateChannel(tx *bolt.Tx, channelID string, update func(c *localChannel) error) error {
+	c, err := getChannel(tx, channelID)
+	if err != nil {
+		return err
+	}
+	if c == nil {
+		return errors.Err("channel %s is not in the job store", channelID)
+	}
+	err = update(c)
+	if err != nil {
+		return err
+	}
+	c.UpdatedAt = time.Now().Unix()
+	return putChannel(tx, c)
+}
+
+// FetchChannels returns the channels in the given status, in the order they were added. Like with the API, the channels
+// being synced are only the ones synced by this server and cp filters on when the channels were added
+func (l *LocalStore) FetchChannels(status string, cp *SyncProperties) ([]YoutubeChannel, error) {
+	var found []localChannel
+	err := l.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(channelsBucket).ForEach(func(k, v []byte) error {
+			var c localChannel
+			err := json.Unmarshal(v, &c)
+			if err != nil {
+				return errors.Err(err)
+			}
+			if cp.YoutubeChannelID != "" && c.ChannelId != cp.YoutubeChannelID {
+				return nil
+			}
+			if status != "" && c.Status != status {
+				return nil
+			}
+			if status == channelStatusSyncing && c.SyncServer != l.hostName {
+				return nil
+			}
+			if (cp.SyncFrom > 0 && c.AddedAt < cp.SyncFrom) || (cp.SyncUntil > 0 && c.AddedAt > cp.SyncUntil) {
+				return nil
+			}
+			found = append(found, c)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].AddedAt < found[j].AddedAt
+	})
+	channels := make([]YoutubeChannel, 0, len(found))
+	for _, c := range found {
+		channels = append(channels, c.YoutubeChannel)
+	}
+	return channels, nil
+}
+
+func (l *LocalStore) SetChannelStatus(channelID string, status string, failureReason string, transferState *int) (map[string]SyncedVideo, map[string]bool, error) {
+	sanitizeFailureReason(&failureReason)
+	svs := make(map[string]SyncedVideo)
+	claimNames := make(map[string]bool)
+	err := l.db.Update(func(tx *bolt.Tx) error {
+		err := l.updateChannel(tx, channelID, func(c *localChannel) error {
+			if status == channelStatusSyncing && c.Status == channelStatusSyncing && c.SyncServer != "" && c.SyncServer != l.hostName {
+				return errors.Err("this youtube channel is being managed by another server")
+			}
+			c.Status = status
+			c.FailureReason = failureReason
+			c.SyncServer = l.hostName
+			if transferState != nil {
+				c.TransferState = *transferState
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		videos := tx.Bucket(videosBucket).Bucket([]byte(channelID))
+		if videos == nil {
+			return nil
+		}
+		return videos.ForEach(func(k, v []byte) error {
+			var sv SyncedVideo
+			err := json.Unmarshal(v, &sv)
+			if err != nil {
+				return errors.Err(err)
+			}
+			svs[sv.VideoID] = sv
+			if sv.ClaimName != "" {
+				claimNames[sv.ClaimName] = sv.Published
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return svs, claimNames, nil
+}
+
+func (l *LocalStore) SetChannelClaimID(channelID string, channelClaimID string) error {
+	return l.db.Update(func(tx *bolt.Tx) error {
+		return l.updateChannel(tx, channelID, func(c *localChannel) error {
+			c.ChannelClaimID = channelClaimID
+			return nil
+		})
+	})
+}
+
+// SetChannelCert stores the certificate of the channel claim with the given ID
+func (l *LocalStore) SetChannelCert(certHex string, channelID string) error {
+	return l.db.Update(func(tx *bolt.Tx) error {
+		var youtubeChannelID string
+		err := tx.Bucket(channelsBucket).ForEach(func(k, v []byte) error {
+			var c localChannel
+			err := json.Unmarshal(v, &c)
+			if err != nil {
+				return errors.Err(err)
+			}
+			if c.ChannelClaimID == channelID {
+				youtubeChannelID = c.ChannelId
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if youtubeChannelID == "" {
+			return errors.Err("no channel with claim ID %s in the job store", channelID)
+		}
+		return l.updateChannel(tx, youtubeChannelID, func(c *localChannel) error {
+			c.ChannelCert = certHex
+			return nil
+		})
+	})
+}
+
+func (l *LocalStore) MarkVideoStatus(status VideoStatus) error {
+	return l.MarkVideoStatuses([]VideoStatus{status})
+}
+
+// MarkVideoStatuses stores the statuses in a single transaction
+func (l *LocalStore) MarkVideoStatuses(statuses []VideoStatus) error {
+	for i := range statuses {
+		sanitizeFailureReason(&statuses[i].FailureReason)
+		err := statuses[i].validate()
+		if err != nil {
+			return err
+		}
+	}
+	return l.db.Update(func(tx *bolt.Tx) error {
+		for _, status := range statuses {
+			err := markVideoStatus(tx, status)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func markVideoStatus(tx *bolt.Tx, status VideoStatus) error {
+	videos, err := tx.Bucket(videosBucket).CreateBucketIfNotExists([]byte(status.ChannelID))
+	if err != nil {
+		return errors.Err(err)
+	}
+	sv := SyncedVideo{VideoID: status.VideoID}
+	if value := videos.Get([]byte(status.VideoID)); value != nil {
+		err = json.Unmarshal(value, &sv)
+		if err != nil {
+			return errors.Err(err)
+		}
+	}
+	sv.Status = status.Status
+	sv.Published = status.hasClaim()
+	sv.FailureReason = status.FailureReason
+	if status.hasClaim() {
+		sv.ClaimID = status.ClaimID
+		sv.ClaimName = status.ClaimName
+		if status.MetaDataVersion > 0 {
+			sv.MetadataVersion = int8(status.MetaDataVersion)
+		}
+		if status.Size != nil {
+			sv.Size = *status.Size
+		}
+		if status.MetadataFingerprint != "" {
+			sv.MetadataFingerprint = status.MetadataFingerprint
+		}
+	}
+	if status.IsTransferred != nil {
+		sv.Transferred = *status.IsTransferred
+	}
+	value, err := json.Marshal(sv)
+	if err != nil {
+		return errors.Err(err)
+	}
+	return errors.Err(videos.Put([]byte(status.VideoID), value))
+}
+
+func (l *LocalStore) DeleteVideos(videos []string) error {
+	return l.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(videosBucket).ForEach(func(channelID, v []byte) error {
+			channelVideos := tx.Bucket(videosBucket).Bucket(channelID)
+			if channelVideos == nil {
+				return nil
+			}
+			for _, id := range videos {
+				err := channelVideos.Delete([]byte(id))
+				if err != nil {
+					return errors.Err(err)
+				}
+			}
+			return nil
+		})
+	})
+}
+
+func (l *LocalStore) FetchSyncedCollections(channelID string) (map[string]SyncedCollection, error) {
+	collections := make(map[string]SyncedCollection)
+	err := l.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(collectionsBucket).Bucket([]byte(channelID))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var c SyncedCollection
+			err := json.Unmarshal(v, &c)
+			if err != nil {
+				return errors.Err(err)
+			}
+			collections[c.PlaylistID] = c
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return collections, nil
+}
+
+func (l *LocalStore) MarkCollectionStatus(channelID string, collection SyncedCollection) error {
+	return l.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket(collectionsBucket).CreateBucketIfNotExists([]byte(channelID))
+		if err != nil {
+			return errors.Err(err)
+		}
+		value, err := json.Marshal(collection)
+		if err != nil {
+			return errors.Err(err)
+		}
+		return errors.Err(b.Put([]byte(collection.PlaylistID), value))
+	})
+}
@@ -0,0 +1,140 @@
+package sdk
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lbryio/lbry.go/v2/extras/util"
+)
+
+func openTestStore(t *testing.T, hostName string) (*LocalStore, string) {
+	dir, err := ioutil.TempDir("", "ytsync-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := OpenLocalStore(filepath.Join(dir, "jobs.db"), hostName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, dir
+}
+
+func TestLocalStoreChannels(t *testing.T) {
+	store, dir := openTestStore(t, "ytsync-1")
+	defer os.RemoveAll(dir)
+	defer store.Close()
+
+	for _, id := range []string{"UCaaa", "UCbbb"} {
+		err := store.AddChannel(YoutubeChannel{ChannelId: id, DesiredChannelName: "@" + id})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := store.AddChannel(YoutubeChannel{ChannelId: "UCaaa", DesiredChannelName: "@renamed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	channels, err := store.FetchChannels(channelStatusQueued, &SyncProperties{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(channels) != 2 || channels[0].DesiredChannelName != "@UCaaa" {
+		t.Fatalf("expected the 2 channels as they were first added, got %+v", channels)
+	}
+
+	_, _, err = store.SetChannelStatus("UCaaa", channelStatusSyncing, "", util.PtrToInt(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.SetChannelClaimID("UCaaa", "abcdef")
+	if err != nil {
+		t.Fatal(err)
+	}
+	channels, err = store.FetchChannels(channelStatusSyncing, &SyncProperties{YoutubeChannelID: "UCaaa"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(channels) != 1 || channels[0].ChannelClaimID != "abcdef" || channels[0].TransferState != 1 {
+		t.Errorf("the state of the channel was not kept: %+v", channels)
+	}
+	err = store.SetChannelCert("cert", "abcdef")
+	if err != nil {
+		t.Error(err)
+	}
+	if err := store.SetChannelCert("cert", "unknown"); err == nil {
+		t.Error("setting the cert of an unknown claim must fail")
+	}
+
+	other := &LocalStore{db: store.db, hostName: "ytsync-2"}
+	if channels, _ := other.FetchChannels(channelStatusSyncing, &SyncProperties{}); len(channels) != 0 {
+		t.Errorf("channels synced by another server must not be returned: %+v", channels)
+	}
+	if _, _, err := other.SetChannelStatus("UCaaa", channelStatusSyncing, "", nil); err == nil {
+		t.Error("a channel synced by another server must not be taken over")
+	}
+	if _, _, err := store.SetChannelStatus("UCccc", channelStatusSyncing, "", nil); err == nil {
+		t.Error("unknown channels must be rejected")
+	}
+}
+
+func TestLocalStoreVideos(t *testing.T) {
+	store, dir := openTestStore(t, "ytsync-1")
+	defer os.RemoveAll(dir)
+	defer store.Close()
+
+	err := store.AddChannel(YoutubeChannel{ChannelId: "UCaaa", DesiredChannelName: "@aaa"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.MarkVideoStatus(VideoStatus{ChannelID: "UCaaa", VideoID: "v1", Status: VideoStatusPublished})
+	if err == nil {
+		t.Error("published videos need a claim")
+	}
+	for _, status := range []VideoStatus{
+		{ChannelID: "UCaaa", VideoID: "v1", Status: VideoStatusPublished, ClaimID: "c1", ClaimName: "video-1", Size: util.PtrToInt64(42), MetaDataVersion: 2},
+		{ChannelID: "UCaaa", VideoID: "v2", Status: VideoStatusFailed, FailureReason: "the video is too big to sync, skipping for now"},
+		{ChannelID: "UCaaa", VideoID: "v3", Status: VideoStatusPublished, ClaimID: "c3", ClaimName: "video-3"},
+		{ChannelID: "UCaaa", VideoID: "v1", Status: VideoStatusPublished, ClaimID: "c1", ClaimName: "video-1", IsTransferred: util.PtrToBool(true)},
+	} {
+		err = store.MarkVideoStatus(status)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = store.DeleteVideos([]string{"v3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svs, claimNames, err := store.SetChannelStatus("UCaaa", channelStatusSyncing, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(svs) != 2 {
+		t.Fatalf("expected 2 videos, got %+v", svs)
+	}
+	if v := svs["v1"]; !v.Published || v.Size != 42 || v.MetadataVersion != 2 || !v.Transferred {
+		t.Errorf("the updates of the video must be merged: %+v", v)
+	}
+	if v := svs["v2"]; v.Published || v.FailureReason == "" {
+		t.Errorf("unexpected failed video: %+v", v)
+	}
+	if len(claimNames) != 1 || !claimNames["video-1"] {
+		t.Errorf("unexpected claim names: %+v", claimNames)
+	}
+
+	err = store.MarkCollectionStatus("UCaaa", SyncedCollection{PlaylistID: "PL1", ClaimID: "c4", Description: "d", Items: []string{"c1"}, Transferred: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	collections, err := store.FetchSyncedCollections("UCaaa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := collections["PL1"]; !ok || c.ClaimID != "c4" || c.Description != "d" || len(c.Items) != 1 || !c.Transferred {
+		t.Errorf("unexpected collections: %+v", collections)
+	}
+}
@@ -0,0 +1,124 @@
+package sdk
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"regexp"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+)
+
+// ChannelPolicy holds the settings of a channel that differ from the global ones. Fields that aren't set keep the
+// global value, which is why the numeric ones are pointers: 0 disables the corresponding limit.
+type ChannelPolicy struct {
+	// MaxVideoSize is expressed in MB
+	MaxVideoSize *int `json:"max_video_size,omitempty"`
+	// MaxVideoLength is expressed in hours
+	MaxVideoLength *float64 `json:"max_video_length,omitempty"`
+	VideosLimit    *int     `json:"videos_limit,omitempty"`
+	Qualities      []string `json:"qualities,omitempty"`
+	Fee            *Fee     `json:"fee,omitempty"`
+	// Tags are added to every claim of the channel
+	Tags                []string `json:"tags,omitempty"`
+	DescriptionTemplate string   `json:"description_template,omitempty"`
+	License             string   `json:"license,omitempty"`
+	LicenseURL          string   `json:"license_url,omitempty"`
+	RemovedVideosPolicy string   `json:"removed_videos_policy,omitempty"`
+	ShortsPolicy        string   `json:"shorts_policy,omitempty"`
+	// ShortsChannelID is the LBRY channel Shorts are published under when the shorts policy is "channel"
+	ShortsChannelID string     `json:"shorts_channel_id,omitempty"`
+	Skip            *SkipRules `json:"skip,omitempty"`
+	// FeedURL is the RSS/Atom feed the channel mirrors with the feed source
+	FeedURL string `json:"feed_url,omitempty"`
+}
+
+// SkipRules describe what isn't synced
+type SkipRules struct {
+	// Channel is the reason why the channel isn't synced at all, the channel is synced when it's empty
+	Channel  string   `json:"channel,omitempty"`
+	VideoIDs []string `json:"video_ids,omitempty"`
+	// TitlePatterns are regular expressions matched against the titles of the videos
+	TitlePatterns []string `json:"title_patterns,omitempty"`
+}
+
+// Merge returns a copy of p where the fields set in override replace the ones of p
+func (p ChannelPolicy) Merge(override *ChannelPolicy) ChannelPolicy {
+	if override == nil {
+		return p
+	}
+	if override.MaxVideoSize != nil {
+		p.MaxVideoSize = override.MaxVideoSize
+	}
+	if override.MaxVideoLength != nil {
+		p.MaxVideoLength = override.MaxVideoLength
+	}
+	if override.VideosLimit != nil {
+		p.VideosLimit = override.VideosLimit
+	}
+	if len(override.Qualities) > 0 {
+		p.Qualities = override.Qualities
+	}
+	if override.Fee != nil {
+		p.Fee = override.Fee
+	}
+	if len(override.Tags) > 0 {
+		p.Tags = override.Tags
+	}
+	if override.DescriptionTemplate != "" {
+		p.DescriptionTemplate = override.DescriptionTemplate
+	}
+	if override.License != "" {
+		p.License = override.License
+		p.LicenseURL = override.LicenseURL
+	}
+	if override.RemovedVideosPolicy != "" {
+		p.RemovedVideosPolicy = override.RemovedVideosPolicy
+	}
+	if override.ShortsPolicy != "" {
+		p.ShortsPolicy = override.ShortsPolicy
+	}
+	if override.ShortsChannelID != "" {
+		p.ShortsChannelID = override.ShortsChannelID
+	}
+	if override.Skip != nil {
+		p.Skip = override.Skip
+	}
+	if override.FeedURL != "" {
+		p.FeedURL = override.FeedURL
+	}
+	return p
+}
+
+// Validate checks the parts of the policy that can be checked without knowing the global settings
+func (p ChannelPolicy) Validate() error {
+	if p.Skip == nil {
+		return nil
+	}
+	for _, pattern := range p.Skip.TitlePatterns {
+		_, err := regexp.Compile(pattern)
+		if err != nil {
+			return errors.Prefix("invalid title pattern", errors.Err(err))
+		}
+	}
+	return nil
+}
+
+// LoadChannelPolicies reads a JSON file mapping youtube channel IDs to their policy
+func LoadChannelPolicies(path string) (map[string]ChannelPolicy, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	policies := make(map[string]ChannelPolicy)
+	err = json.Unmarshal(content, &policies)
+	if err != nil {
+		return nil, errors.Prefix("invalid channel policies file", errors.Err(err))
+	}
+	for channelID, policy := range policies {
+		err = policy.Validate()
+		if err != nil {
+			return nil, errors.Prefix(channelID, err)
+		}
+	}
+	return policies, nil
+}
@@ -0,0 +1,42 @@
+package sdk
+
+import (
+	"testing"
+
+	"github.com/lbryio/lbry.go/v2/extras/util"
+)
+
+func TestChannelPolicyMerge(t *testing.T) {
+	defaults := ChannelPolicy{
+		MaxVideoSize:   util.PtrToInt(2048),
+		MaxVideoLength: util.PtrToFloat64(2),
+		VideosLimit:    util.PtrToInt(1000),
+		Qualities:      []string{"1080", "720"},
+		ShortsPolicy:   "publish",
+		Skip:           &SkipRules{},
+		FeedURL:        "https://example.com/default.xml",
+	}
+	merged := defaults.Merge(&ChannelPolicy{
+		MaxVideoSize:   util.PtrToInt(0),
+		MaxVideoLength: util.PtrToFloat64(0),
+		ShortsPolicy:   "skip",
+		Skip:           &SkipRules{VideoIDs: []string{"dQw4w9WgXcQ"}},
+		FeedURL:        "https://example.com/channel.xml",
+	})
+	if *merged.MaxVideoSize != 0 || *merged.MaxVideoLength != 0 {
+		t.Errorf("explicit zeroes must disable the limits: %+v", merged)
+	}
+	if *merged.VideosLimit != 1000 || len(merged.Qualities) != 2 {
+		t.Errorf("unset fields must keep the defaults: %+v", merged)
+	}
+	if merged.ShortsPolicy != "skip" || len(merged.Skip.VideoIDs) != 1 || merged.FeedURL != "https://example.com/channel.xml" {
+		t.Errorf("set fields must be overridden: %+v", merged)
+	}
+	if *defaults.MaxVideoSize != 2048 || len(defaults.Skip.VideoIDs) != 0 {
+		t.Errorf("merging must not alter the defaults: %+v", defaults)
+	}
+
+	if merged := defaults.Merge(nil); *merged.VideosLimit != 1000 {
+		t.Errorf("a nil policy must keep the defaults: %+v", merged)
+	}
+}
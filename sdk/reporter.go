@@ -0,0 +1,262 @@
+package sdk
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// StatusBatchSize is how many statuses are reported at most in a single call
+	StatusBatchSize = 50
+	// statusFlushInterval is how often the pending statuses are reported
+	statusFlushInterval = 10 * time.Second
+)
+
+// StatusReporter reports the statuses of the videos to the job store in batches. The statuses waiting to be reported
+// are written to a journal first, so the ones that couldn't be reported, because the store is unreachable or ytsync
+// stopped, are reported on the next start instead of being lost
+type StatusReporter struct {
+	store       JobStore
+	journalPath string
+
+	// mux guards pending and journal
+	mux     sync.Mutex
+	pending []VideoStatus
+	journal *os.File
+	// flushMux makes sure the statuses are reported one batch at a time, in order
+	flushMux sync.Mutex
+
+	flushCh chan struct{}
+	stopCh  chan struct{}
+	done    chan struct{}
+}
+
+// NewStatusReporter creates a reporter for the store. journalPath can be empty to keep the pending statuses in memory
+func NewStatusReporter(store JobStore, journalPath string) *StatusReporter {
+	return &StatusReporter{
+		store:       store,
+		journalPath: journalPath,
+		flushCh:     make(chan struct{}, 1),
+		stopCh:      make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+// Start loads the statuses left in the journal by the previous run and starts reporting them in the background
+func (r *StatusReporter) Start() error {
+	if r.journalPath != "" {
+		pending, err := readJournal(r.journalPath)
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			log.Infof("replaying %d video statuses from %s", len(pending), r.journalPath)
+		}
+		r.pending = pending
+		err = os.MkdirAll(filepath.Dir(r.journalPath), 0755)
+		if err != nil {
+			return errors.Err(err)
+		}
+		r.journal, err = os.OpenFile(r.journalPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return errors.Err(err)
+		}
+	}
+	go r.run()
+	r.requestFlush()
+	return nil
+}
+
+func readJournal(path string) ([]VideoStatus, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	defer f.Close()
+	var statuses []VideoStatus
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var status VideoStatus
+		err := json.Unmarshal(scanner.Bytes(), &status)
+		if err != nil {
+			// a line can only be broken if ytsync died while writing it, it was never acknowledged
+			log.Errorf("skipping a broken line of the status journal: %s", err.Error())
+			continue
+		}
+		statuses = append(statuses, status)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Err(err)
+	}
+	return statuses, nil
+}
+
+func (r *StatusReporter) run() {
+	defer close(r.done)
+	ticker := time.NewTicker(statusFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stopCh:
+			return
+		case <-ticker.C:
+		case <-r.flushCh:
+		}
+		err := r.Flush()
+		if err != nil {
+			log.Errorf("could not report the video statuses, they'll be retried: %s", err.Error())
+		}
+	}
+}
+
+func (r *StatusReporter) requestFlush() {
+	select {
+	case r.flushCh <- struct{}{}:
+	default:
+	}
+}
+
+// Report queues the status. It's only lost if it's invalid, which is reported right away
+func (r *StatusReporter) Report(status VideoStatus) error {
+	sanitizeFailureReason(&status.FailureReason)
+	err := status.validate()
+	if err != nil {
+		return err
+	}
+	if status.PublishedAt == 0 {
+		status.PublishedAt = time.Now().Unix()
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if r.journal != nil {
+		line, err := json.Marshal(status)
+		if err != nil {
+			return errors.Err(err)
+		}
+		_, err = r.journal.Write(append(line, '\n'))
+		if err == nil {
+			err = r.journal.Sync()
+		}
+		if err != nil {
+			return errors.Prefix("could not write the status journal", err)
+		}
+	}
+	r.pending = append(r.pending, status)
+	if len(r.pending) >= StatusBatchSize {
+		r.requestFlush()
+	}
+	return nil
+}
+
+// Durable returns true if the queued statuses survive a crash, which is the case when they're journaled. Otherwise
+// they're only safe once they were flushed
+func (r *StatusReporter) Durable() bool {
+	return r.journalPath != ""
+}
+
+// Pending returns how many statuses are waiting to be reported
+func (r *StatusReporter) Pending() int {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return len(r.pending)
+}
+
+// Flush reports all the pending statuses. It stops at the first batch that can't be reported
+func (r *StatusReporter) Flush() error {
+	r.flushMux.Lock()
+	defer r.flushMux.Unlock()
+	for {
+		r.mux.Lock()
+		n := len(r.pending)
+		if n > StatusBatchSize {
+			n = StatusBatchSize
+		}
+		batch := append([]VideoStatus(nil), r.pending[:n]...)
+		r.mux.Unlock()
+		if len(batch) == 0 {
+			return nil
+		}
+
+		err := r.store.MarkVideoStatuses(batch)
+		if err != nil {
+			return err
+		}
+
+		r.mux.Lock()
+		r.pending = r.pending[len(batch):]
+		err = r.rewriteJournal()
+		r.mux.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// rewriteJournal replaces the journal with the statuses still pending. r.mux must be held
+func (r *StatusReporter) rewriteJournal() error {
+	if r.journal == nil {
+		return nil
+	}
+	tmpPath := r.journalPath + ".tmp"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return errors.Err(err)
+	}
+	w := bufio.NewWriter(tmp)
+	for _, status := range r.pending {
+		line, err := json.Marshal(status)
+		if err != nil {
+			_ = tmp.Close()
+			return errors.Err(err)
+		}
+		_, _ = w.Write(append(line, '\n'))
+	}
+	err = w.Flush()
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.Err(err)
+	}
+	err = os.Rename(tmpPath, r.journalPath)
+	if err != nil {
+		return errors.Err(err)
+	}
+	_ = r.journal.Close()
+	r.journal, err = os.OpenFile(r.journalPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	return errors.Err(err)
+}
+
+// Close stops the background reporting and reports what's still pending. Whatever can't be reported stays in the
+// journal
+func (r *StatusReporter) Close() error {
+	close(r.stopCh)
+	<-r.done
+	err := r.Flush()
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if r.journal != nil {
+		_ = r.journal.Close()
+		r.journal = nil
+	}
+	if err != nil {
+		return errors.Prefix("some video statuses could not be reported", err)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package sdk
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+)
+
+// flakyStore records the statuses reported to it, unless it's down
+type flakyStore struct {
+	JobStore
+	mux      sync.Mutex
+	down     bool
+	reported []VideoStatus
+}
+
+func (f *flakyStore) MarkVideoStatuses(statuses []VideoStatus) error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	if f.down {
+		return errors.Err("connect: connection refused")
+	}
+	f.reported = append(f.reported, statuses...)
+	return nil
+}
+
+func (f *flakyStore) setDown(down bool) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	f.down = down
+}
+
+func TestStatusReporterJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ytsync-reporter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	journal := filepath.Join(dir, "journal", "statuses.jsonl")
+
+	store := &flakyStore{down: true}
+	r := NewStatusReporter(store, journal)
+	if !r.Durable() || NewStatusReporter(store, "").Durable() {
+		t.Error("only the journaled statuses survive a crash")
+	}
+	err = r.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Report(VideoStatus{ChannelID: "UCaaa", VideoID: "v0", Status: VideoStatusPublished}); err == nil {
+		t.Error("invalid statuses must be rejected right away")
+	}
+	for i := 0; i < StatusBatchSize+10; i++ {
+		err = r.Report(VideoStatus{ChannelID: "UCaaa", VideoID: "v1", Status: VideoStatusPublished, ClaimID: "c1", ClaimName: "video-1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Close(); err == nil {
+		t.Error("the statuses that couldn't be reported must be reported as such")
+	}
+
+	store.setDown(false)
+	r = NewStatusReporter(store, journal)
+	err = r.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Report(VideoStatus{ChannelID: "UCaaa", VideoID: "v2", Status: VideoStatusFailed, FailureReason: "too big"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(store.reported) != StatusBatchSize+11 {
+		t.Fatalf("expected the journal to be replayed, got %d statuses", len(store.reported))
+	}
+	last := store.reported[len(store.reported)-1]
+	if last.VideoID != "v2" || store.reported[0].PublishedAt == 0 {
+		t.Errorf("the statuses must be reported in order with the time they were reported at: %+v", last)
+	}
+
+	pending, err := readJournal(journal)
+	if err != nil || len(pending) != 0 {
+		t.Errorf("the journal must be emptied once the statuses are reported: %d left (%v)", len(pending), err)
+	}
+}
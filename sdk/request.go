@@ -0,0 +1,206 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	"github.com/lbryio/lbry.go/v2/extras/null"
+
+	"github.com/lbryio/ytsync/errclass"
+	"github.com/lbryio/ytsync/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultAPITimeout = 3 * time.Minute
+
+var ErrAPIInterrupted = errclass.New(errclass.Interrupted, "API call interrupted by user")
+
+// RetryPolicy tells how failed calls to the API are retried: after an exponential backoff with jitter, until either
+// limit is reached
+type RetryPolicy struct {
+	// MaxAttempts is how many times a call is made at most, 0 for no limit
+	MaxAttempts int
+	// MaxElapsed is how long a call is retried for at most, 0 for no limit
+	MaxElapsed time.Duration
+	// InitialBackoff is the wait after the first failure, it doubles with every attempt up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DefaultRetryPolicy rides out an outage of the API of about 15 minutes
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    12,
+	MaxElapsed:     15 * time.Minute,
+	InitialBackoff: 2 * time.Second,
+	MaxBackoff:     2 * time.Minute,
+}
+
+// backoff returns how long to wait after the given failed attempt, the first one being 1. Half of it is random so
+// the syncs hitting the API at the same time don't retry at the same time
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	wait := p.InitialBackoff
+	for i := 1; i < attempt && wait < p.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > p.MaxBackoff {
+		wait = p.MaxBackoff
+	}
+	if wait <= 0 {
+		return 0
+	}
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+// retryAfter returns the wait asked for by the Retry-After header of res, either in seconds or as a date, 0 if none
+func retryAfter(res *http.Response, now time.Time) time.Duration {
+	value := res.Header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+// statusError is a call to the API that failed with an unexpected status code
+type statusError struct {
+	endpoint   string
+	statusCode int
+	// message is the error sent by the API, if any
+	message string
+}
+
+func (e *statusError) Error() string {
+	if e.message != "" {
+		return e.message
+	}
+	return fmt.Sprintf("%s returned status %d", e.endpoint, e.statusCode)
+}
+
+// hasStatus returns true if err is a call to the API that failed with the given status code
+func hasStatus(err error, statusCode int) bool {
+	e, ok := errors.Unwrap(err).(*statusError)
+	return ok && e.statusCode == statusCode
+}
+
+// shouldRetry returns true for the status codes of failures that are expected to go away
+func shouldRetry(statusCode int) bool {
+	return statusCode == http.StatusRequestTimeout || statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+func (a *APIConfig) retryPolicy() RetryPolicy {
+	if a.Retry == (RetryPolicy{}) {
+		return DefaultRetryPolicy
+	}
+	return a.Retry
+}
+
+func (a *APIConfig) client() *http.Client {
+	if a.Client == nil {
+		return &http.Client{Timeout: defaultAPITimeout}
+	}
+	return a.Client
+}
+
+// context returns a context that is cancelled once the stop group of the API is stopped
+func (a *APIConfig) context() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if a.Stop != nil {
+		go func() {
+			select {
+			case <-a.Stop.Ch():
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+	return ctx, cancel
+}
+
+// post sends the form to the endpoint and returns the body of the response. Network errors, timeouts, throttling and
+// server errors are retried according to the retry policy. Other failures return the error sent by the API, if any
+func (a *APIConfig) post(endpoint string, params url.Values) ([]byte, error) {
+	ctx, cancel := a.context()
+	defer cancel()
+	policy := a.retryPolicy()
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		body, wait, err := a.attempt(ctx, endpoint, params)
+		if err == nil {
+			return body, nil
+		}
+		if ctx.Err() != nil {
+			return nil, errors.Err(ErrAPIInterrupted)
+		}
+		if wait < 0 {
+			return nil, err
+		}
+		if backoff := policy.backoff(attempt); backoff > wait {
+			wait = backoff
+		}
+		elapsed := time.Since(start)
+		if (policy.MaxAttempts > 0 && attempt >= policy.MaxAttempts) || (policy.MaxElapsed > 0 && elapsed+wait > policy.MaxElapsed) {
+			util.SendErrorToSlack("giving up on %s after %d attempts in %s: %s", endpoint, attempt, elapsed.Round(time.Second), err.Error())
+			return nil, errors.Prefix("giving up on "+endpoint, err)
+		}
+		if attempt == 1 {
+			util.SendErrorToSlack("error while calling %s, retrying: %s", endpoint, err.Error())
+		}
+		log.Warnf("attempt %d to call %s failed, retrying in %s: %s", attempt, endpoint, wait.Round(time.Millisecond), err.Error())
+		select {
+		case <-ctx.Done():
+			return nil, errors.Err(ErrAPIInterrupted)
+		case <-time.After(wait):
+		}
+	}
+}
+
+// attempt sends the request once. wait is negative if the request must not be retried, otherwise it's the minimum
+// wait asked for by the API
+func (a *APIConfig) attempt(ctx context.Context, endpoint string, params url.Values) (body []byte, wait time.Duration, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, -1, errors.Err(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res, err := a.client().Do(req)
+	if err != nil {
+		return nil, 0, errors.Err(err)
+	}
+	defer res.Body.Close()
+	body, err = ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, 0, errors.Err(err)
+	}
+	if res.StatusCode == http.StatusOK {
+		return body, 0, nil
+	}
+	apiErr := &statusError{endpoint: endpoint, statusCode: res.StatusCode}
+	var response struct {
+		Error null.String `json:"error"`
+	}
+	if json.Unmarshal(body, &response) == nil && !response.Error.IsNull() {
+		apiErr.message = response.Error.String
+	} else {
+		log.Debugln(string(body))
+	}
+	err = errors.Err(apiErr)
+	if !shouldRetry(res.StatusCode) {
+		return nil, -1, err
+	}
+	return nil, retryAfter(res, time.Now()), err
+}
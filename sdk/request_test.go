@@ -0,0 +1,138 @@
+package sdk
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	"github.com/lbryio/lbry.go/v2/extras/stop"
+)
+
+var testRetryPolicy = RetryPolicy{MaxAttempts: 4, InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+
+// testAPI answers the calls with the given status codes in turn, then with a success
+func testAPI(statuses ...int) (*httptest.Server, *int32) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&calls, 1))
+		if n <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+			_, _ = w.Write([]byte(`{"success": false, "error": "try again", "data": null}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"success": true, "error": null, "data": "ok"}`))
+	}))
+	return server, &calls
+}
+
+func TestPostRetries(t *testing.T) {
+	server, calls := testAPI(http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusBadGateway)
+	defer server.Close()
+	a := &APIConfig{ApiURL: server.URL, Retry: testRetryPolicy}
+	err := a.SetChannelClaimID("UCaaa", "abcdef")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *calls != 4 {
+		t.Errorf("expected 4 calls, got %d", *calls)
+	}
+}
+
+func TestPostGivesUp(t *testing.T) {
+	server, calls := testAPI(500, 500, 500, 500, 500)
+	defer server.Close()
+	a := &APIConfig{ApiURL: server.URL, Retry: testRetryPolicy}
+	err := a.SetChannelClaimID("UCaaa", "abcdef")
+	if err == nil || !strings.Contains(err.Error(), "try again") {
+		t.Errorf("expected the error of the API, got %v", err)
+	}
+	if *calls != 4 {
+		t.Errorf("expected 4 calls, got %d", *calls)
+	}
+
+	server, calls = testAPI(http.StatusBadRequest)
+	defer server.Close()
+	a.ApiURL = server.URL
+	err = a.SetChannelClaimID("UCaaa", "abcdef")
+	if err == nil || *calls != 1 {
+		t.Errorf("client errors must not be retried: %v after %d calls", err, *calls)
+	}
+}
+
+func TestPostInterrupted(t *testing.T) {
+	server, _ := testAPI(500, 500, 500, 500, 500)
+	defer server.Close()
+	grp := stop.New()
+	a := &APIConfig{ApiURL: server.URL, Stop: grp, Retry: RetryPolicy{InitialBackoff: time.Hour, MaxBackoff: time.Hour}}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		grp.Stop()
+	}()
+	done := make(chan error)
+	go func() {
+		done <- a.SetChannelClaimID("UCaaa", "abcdef")
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrAPIInterrupted) {
+			t.Errorf("expected an interruption, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the call was not interrupted")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	for value, expected := range map[string]time.Duration{
+		"":                              0,
+		"120":                           2 * time.Minute,
+		"Wed, 01 Jan 2020 12:00:30 GMT": 30 * time.Second,
+		"Wed, 01 Jan 2020 11:00:00 GMT": 0,
+		"soon":                          0,
+	} {
+		res := &http.Response{Header: http.Header{}}
+		res.Header.Set("Retry-After", value)
+		if wait := retryAfter(res, now); wait != expected {
+			t.Errorf("%q: expected %s, got %s", value, expected, wait)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 10 * time.Second}
+	for attempt, max := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 10: 10 * time.Second} {
+		wait := p.backoff(attempt)
+		if wait < max/2 || wait > max {
+			t.Errorf("attempt %d: %s is not between %s and %s", attempt, wait, max/2, max)
+		}
+	}
+}
+
+func TestMarkVideoStatusesFallback(t *testing.T) {
+	var single int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/yt/video_statuses" {
+			http.NotFound(w, r)
+			return
+		}
+		atomic.AddInt32(&single, 1)
+		_, _ = w.Write([]byte(`{"success": true, "error": null, "data": "ok"}`))
+	}))
+	defer server.Close()
+	a := &APIConfig{ApiURL: server.URL, Retry: testRetryPolicy}
+	err := a.MarkVideoStatuses([]VideoStatus{
+		{ChannelID: "UCaaa", VideoID: "v1", Status: VideoStatusPublished, ClaimID: "c1", ClaimName: "video-1"},
+		{ChannelID: "UCaaa", VideoID: "v2", Status: VideoStatusFailed, FailureReason: "too big"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if single != 2 {
+		t.Errorf("expected the statuses to be reported one by one, got %d calls", single)
+	}
+}
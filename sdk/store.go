@@ -0,0 +1,32 @@
+package sdk
+
+// JobStore keeps track of the channels to sync, of the state of their videos and of the claims they were published as.
+// APIConfig stores them through the internal LBRY web API, LocalStore in an embedded database
+type JobStore interface {
+	// FetchChannels returns the channels in the given sync status, all of them if status is empty
+	FetchChannels(status string, cp *SyncProperties) ([]YoutubeChannel, error)
+	// SetChannelStatus updates the sync status of the channel and returns its synced videos along with the names of
+	// its claims, mapped to whether they're published
+	SetChannelStatus(channelID string, status string, failureReason string, transferState *int) (map[string]SyncedVideo, map[string]bool, error)
+	SetChannelClaimID(channelID string, channelClaimID string) error
+	SetChannelCert(certHex string, channelID string) error
+	MarkVideoStatus(status VideoStatus) error
+	// MarkVideoStatuses reports several statuses at once, in order
+	MarkVideoStatuses(statuses []VideoStatus) error
+	DeleteVideos(videos []string) error
+	FetchSyncedCollections(channelID string) (map[string]SyncedCollection, error)
+	MarkCollectionStatus(channelID string, collection SyncedCollection) error
+	Close() error
+}
+
+const (
+	JobStoreAPI   = "api"
+	JobStoreLocal = "local"
+)
+
+var JobStores = []string{JobStoreAPI, JobStoreLocal}
+
+// Close does nothing, the API holds no resources
+func (a *APIConfig) Close() error {
+	return nil
+}
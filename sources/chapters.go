@@ -0,0 +1,65 @@
+package sources
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/lbryio/ytsync/media"
+)
+
+const timestampPattern = `\(?((?:\d{1,2}:)?\d{1,2}:\d{2})\)?`
+
+var (
+	// "0:00 Intro", "00:00 - Intro", "(1:02:03) Intro"
+	timestampFirst = regexp.MustCompile(`^[\s\-*•]*` + timestampPattern + `(?:\s*[-–—:|]\s*|\s+)(\S.*?)\s*$`)
+	// "Intro 0:00", "Intro - 00:00"
+	titleFirst = regexp.MustCompile(`^[\s\-*•]*(\S.*?)(?:\s*[-–—:|]\s*|\s+)` + timestampPattern + `\s*$`)
+)
+
+// minChapters is the least amount of timestamps youtube turns into chapters
+const minChapters = 3
+
+// parseTimestamp converts a [hh:]mm:ss timestamp to seconds
+func parseTimestamp(timestamp string) float64 {
+	seconds := 0
+	for _, part := range strings.Split(timestamp, ":") {
+		n, _ := strconv.Atoi(part)
+		seconds = seconds*60 + n
+	}
+	return float64(seconds)
+}
+
+func parseChapterLine(line string) (start float64, title string, ok bool) {
+	if m := timestampFirst.FindStringSubmatch(line); m != nil {
+		return parseTimestamp(m[1]), m[2], true
+	}
+	if m := titleFirst.FindStringSubmatch(line); m != nil {
+		return parseTimestamp(m[2]), m[1], true
+	}
+	return 0, "", false
+}
+
+// splitChapters extracts the chapter list from a description the way youtube does: the list starts with a 0:00
+// timestamp and is made of at least 3 increasing timestamps. It returns the chapters, the lines they were parsed from
+// and the rest of the description. The end of the last chapter is unknown and left at 0.
+func splitChapters(description string) (chapters []media.Chapter, chapterList string, rest string) {
+	lines := strings.Split(description, "\n")
+	var chapterLines, otherLines []string
+	for _, line := range lines {
+		start, title, ok := parseChapterLine(line)
+		if ok && (len(chapters) == 0 && start == 0 || len(chapters) > 0 && start > chapters[len(chapters)-1].Start) {
+			if len(chapters) > 0 {
+				chapters[len(chapters)-1].End = start
+			}
+			chapters = append(chapters, media.Chapter{Start: start, Title: title})
+			chapterLines = append(chapterLines, line)
+			continue
+		}
+		otherLines = append(otherLines, line)
+	}
+	if len(chapters) < minChapters {
+		return nil, "", description
+	}
+	return chapters, strings.Join(chapterLines, "\n"), strings.Join(otherLines, "\n")
+}
@@ -0,0 +1,34 @@
+package sources
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitChapters(t *testing.T) {
+	description := "A long talk about things.\n\n0:00 Intro\n1:30 - The first thing\n(12:05) The second thing\nThe end 1:02:03\n\nRecorded at 10:30 in the morning"
+	chapters, chapterList, rest := splitChapters(description)
+	if len(chapters) != 4 {
+		t.Fatalf("expected 4 chapters, got %+v", chapters)
+	}
+	expected := []struct {
+		start float64
+		title string
+	}{{0, "Intro"}, {90, "The first thing"}, {725, "The second thing"}, {3723, "The end"}}
+	for i, e := range expected {
+		if chapters[i].Start != e.start || chapters[i].Title != e.title {
+			t.Errorf("chapter %d: expected %+v, got %+v", i, e, chapters[i])
+		}
+	}
+	if chapters[0].End != 90 || chapters[3].End != 0 {
+		t.Errorf("unexpected chapter ends: %+v", chapters)
+	}
+	if strings.Count(chapterList, "\n") != 3 || strings.Contains(rest, "Intro") || !strings.Contains(rest, "Recorded at 10:30") {
+		t.Errorf("unexpected split:\n%s\n---\n%s", chapterList, rest)
+	}
+
+	_, chapterList, rest = splitChapters("Timestamps:\n2:00 not a chapter\n3:00 list")
+	if chapterList != "" || rest != "Timestamps:\n2:00 not a chapter\n3:00 list" {
+		t.Errorf("lists that don't start at 0:00 aren't chapters")
+	}
+}
@@ -0,0 +1,119 @@
+package sources
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+
+	"github.com/lbryio/ytsync/media"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxDescriptionLength is the length descriptions from the source are shortened to
+const maxDescriptionLength = 2800
+
+// DefaultDescriptionTemplate is the template of the descriptions of channels that don't have one
+const DefaultDescriptionTemplate = "{{.Description}}{{if .URL}}\n...\n{{.URL}}{{end}}"
+
+// DescriptionData holds the fields available to description templates
+type DescriptionData struct {
+	ID    string
+	Title string
+	// Description is the description on the source, shortened to fit in a claim. Chapter lists are kept whole
+	Description string
+	// FullDescription is the description on the source as it is
+	FullDescription string
+	PublishedAt     time.Time
+	// URL is the address of the video on the source, it's empty if the source doesn't have one
+	URL         string
+	ChannelName string
+	Duration    time.Duration
+	Chapters    []media.Chapter
+}
+
+var descriptionFuncs = template.FuncMap{
+	"truncate":  truncate,
+	"timestamp": formatTimestamp,
+}
+
+var defaultDescriptionTemplate = template.Must(ParseDescriptionTemplate(DefaultDescriptionTemplate))
+
+// ParseDescriptionTemplate parses a text/template that renders claim descriptions from DescriptionData. Besides the
+// builtin functions, templates can use "truncate" to shorten a string to a length and "timestamp" to format seconds
+// like youtube chapters do.
+func ParseDescriptionTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("description").Funcs(descriptionFuncs).Parse(text)
+	if err != nil {
+		return nil, errors.Prefix("invalid description template", errors.Err(err))
+	}
+	//fields are only resolved when executing, so catch typos now rather than when publishing
+	err = tmpl.Execute(ioutil.Discard, DescriptionData{})
+	if err != nil {
+		return nil, errors.Prefix("invalid description template", errors.Err(err))
+	}
+	return tmpl, nil
+}
+
+func renderDescription(tmpl *template.Template, data DescriptionData) (string, error) {
+	if tmpl == nil {
+		tmpl = defaultDescriptionTemplate
+	}
+	var b strings.Builder
+	err := tmpl.Execute(&b, data)
+	if err != nil {
+		return "", errors.Err(err)
+	}
+	return b.String(), nil
+}
+
+// describe renders the description template of the channel, tmpl, for a video. The default template is used if the
+// one of the channel fails
+func describe(tmpl *template.Template, data DescriptionData) string {
+	description, err := renderDescription(tmpl, data)
+	if err != nil {
+		log.Errorf("%s: failed to render the description template, using the default one: %s", data.ID, err.Error())
+		description, _ = renderDescription(nil, data)
+	}
+	return description
+}
+
+// abbreviateDescription shortens description to maxDescriptionLength. Chapter lists are often at the end of long
+// descriptions, so they're moved ahead of what's left to keep them whole.
+func abbreviateDescription(description string) string {
+	description = strings.TrimSpace(description)
+	if len(description) <= maxDescriptionLength {
+		return description
+	}
+	_, chapterList, rest := splitChapters(description)
+	if chapterList == "" || len(chapterList)+2 >= maxDescriptionLength {
+		return truncate(description, maxDescriptionLength)
+	}
+	rest = truncate(strings.TrimSpace(rest), maxDescriptionLength-len(chapterList)-2)
+	return chapterList + "\n\n" + rest
+}
+
+// truncate shortens s to at most length bytes without splitting a character
+func truncate(s string, length int) string {
+	if len(s) <= length {
+		return s
+	}
+	for length > 0 && !utf8.RuneStart(s[length]) {
+		length--
+	}
+	return s[:length]
+}
+
+// formatTimestamp formats seconds as [h:]mm:ss
+func formatTimestamp(seconds float64) string {
+	s := int(seconds)
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
@@ -0,0 +1,87 @@
+package sources
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lbryio/ytsync/media"
+)
+
+func TestRenderDescription(t *testing.T) {
+	data := DescriptionData{
+		ID:          "dQw4w9WgXcQ",
+		Description: "A video",
+		URL:         "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+		ChannelName: "Some Channel",
+		Chapters:    []media.Chapter{{Start: 0, Title: "Intro"}, {Start: 3725, Title: "Outro"}},
+	}
+	description, err := renderDescription(nil, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if description != "A video\n...\nhttps://www.youtube.com/watch?v=dQw4w9WgXcQ" {
+		t.Errorf("unexpected default description: %q", description)
+	}
+
+	tmpl, err := ParseDescriptionTemplate("{{.Description}}\n{{range .Chapters}}{{timestamp .Start}} {{.Title}}\n{{end}}Mirrored from {{.ChannelName}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	description, err = renderDescription(tmpl, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if description != "A video\n0:00 Intro\n1:02:05 Outro\nMirrored from Some Channel" {
+		t.Errorf("unexpected description: %q", description)
+	}
+
+	// the sources without a page for their videos get no link
+	description, err = renderDescription(nil, DescriptionData{Description: "A file"})
+	if err != nil || description != "A file" {
+		t.Errorf("unexpected default description without URL: %q (%v)", description, err)
+	}
+
+	_, err = ParseDescriptionTemplate("{{.Nope}}")
+	if err == nil || !strings.Contains(err.Error(), "invalid description template") {
+		t.Errorf("unknown fields must be rejected, got %v", err)
+	}
+}
+
+func TestAbbreviateDescription(t *testing.T) {
+	chapters := "0:00 Intro\n5:00 Middle\n10:00 End"
+	description := strings.Repeat("a", maxDescriptionLength) + "\n" + chapters
+	abbreviated := abbreviateDescription(description)
+	if len(abbreviated) != maxDescriptionLength || !strings.HasPrefix(abbreviated, chapters+"\n\n") {
+		t.Errorf("the chapter list must be kept ahead of the truncated description: %q...", abbreviated[:60])
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	s := strings.Repeat("é", 3)
+	for length, expected := range map[int]string{6: s, 5: "éé", 4: "éé", 1: "", 0: ""} {
+		if truncated := truncate(s, length); truncated != expected {
+			t.Errorf("%d: expected %q, got %q", length, expected, truncated)
+		}
+	}
+}
+
+func TestDescribeSources(t *testing.T) {
+	tmpl, err := ParseDescriptionTemplate("{{.Title}} by {{.ChannelName}}: {{.URL}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed := &FeedVideo{
+		item:                feedItem{Title: "Episode 1", Link: "https://example.com/ep1", ChannelName: "Test Podcast"},
+		descriptionTemplate: tmpl,
+	}
+	if description := feed.Metadata().Description; description != "Episode 1 by Test Podcast: https://example.com/ep1" {
+		t.Errorf("unexpected feed description: %q", description)
+	}
+	local := &LocalVideo{
+		info:                &ytdlInfo{Title: "Video 1", Uploader: "Uploader", WebpageURL: "https://example.com/v1"},
+		descriptionTemplate: tmpl,
+	}
+	if description := local.Metadata().Description; description != "Video 1 by Uploader: https://example.com/v1" {
+		t.Errorf("unexpected local description: %q", description)
+	}
+}
@@ -0,0 +1,358 @@
+package sources
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	"github.com/lbryio/lbry.go/v2/extras/stop"
+
+	"github.com/aws/aws-sdk-go/aws"
+	log "github.com/sirupsen/logrus"
+)
+
+type feedEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type feedLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr"`
+	Length int64  `xml:"length,attr"`
+}
+
+type feedImage struct {
+	Href string `xml:"href,attr"`
+	URL  string `xml:"url,attr"`
+}
+
+type rssItem struct {
+	Title          string          `xml:"title"`
+	Link           string          `xml:"link"`
+	Description    string          `xml:"description"`
+	GUID           string          `xml:"guid"`
+	PubDate        string          `xml:"pubDate"`
+	Categories     []string        `xml:"category"`
+	Enclosures     []feedEnclosure `xml:"enclosure"`
+	ItunesSummary  string          `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd summary"`
+	ItunesDuration string          `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	ItunesKeywords string          `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd keywords"`
+	ItunesImage    feedImage       `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+	MediaThumbnail feedImage       `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	MediaContent   []feedEnclosure `xml:"http://search.yahoo.com/mrss/ content"`
+	License        string          `xml:"http://backend.userland.com/creativeCommonsRssModule license"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Description   string    `xml:"description"`
+	Language      string    `xml:"language"`
+	ItunesSummary string    `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd summary"`
+	ItunesImage   feedImage `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+	License       string    `xml:"http://backend.userland.com/creativeCommonsRssModule license"`
+	Image         struct {
+		URL string `xml:"url"`
+	} `xml:"image"`
+	Items []rssItem `xml:"item"`
+}
+
+type atomEntry struct {
+	ID         string     `xml:"id"`
+	Title      string     `xml:"title"`
+	Summary    string     `xml:"summary"`
+	Content    string     `xml:"content"`
+	Published  string     `xml:"published"`
+	Updated    string     `xml:"updated"`
+	Links      []feedLink `xml:"link"`
+	Categories []struct {
+		Term string `xml:"term,attr"`
+	} `xml:"category"`
+	MediaGroup struct {
+		Description string          `xml:"http://search.yahoo.com/mrss/ description"`
+		Thumbnail   feedImage       `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+		Content     []feedEnclosure `xml:"http://search.yahoo.com/mrss/ content"`
+	} `xml:"http://search.yahoo.com/mrss/ group"`
+}
+
+// feedDocument can hold either an RSS 2.0 document or an Atom feed
+type feedDocument struct {
+	XMLName  xml.Name
+	Channel  rssChannel  `xml:"channel"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle"`
+	Logo     string      `xml:"logo"`
+	Icon     string      `xml:"icon"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+// feedItem is the format independent representation of an RSS item or an Atom entry
+type feedItem struct {
+	GUID         string
+	Title        string
+	Description  string
+	Link         string
+	PublishedAt  time.Time
+	MediaURL     string
+	MediaSize    int64
+	MediaType    string
+	Duration     float64
+	ThumbnailURL string
+	Tags         []string
+	// ChannelName is the title of the feed
+	ChannelName string
+	// LicenseURL is the license the item is released under, it's empty if the feed doesn't say
+	LicenseURL string
+}
+
+// feedItemID returns the video ID of an item, its GUID. Items without a GUID are identified by their link, or their
+// media. The ID is empty if the item has none of them
+func feedItemID(item feedItem) string {
+	return firstNonEmpty(item.GUID, item.Link, item.MediaURL)
+}
+
+// feedFileName turns a video ID into the name of its files on disk. GUIDs are often URLs: they're hashed rather than
+// rewritten, which would make distinct GUIDs collide
+func feedFileName(videoID string) string {
+	sum := sha256.Sum256([]byte(videoID))
+	return hex.EncodeToString(sum[:16])
+}
+
+var feedDateFormats = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	time.RFC3339,
+	time.RFC3339Nano,
+}
+
+func parseFeedDate(date string) time.Time {
+	date = strings.TrimSpace(date)
+	for _, format := range feedDateFormats {
+		t, err := time.Parse(format, date)
+		if err == nil {
+			return t
+		}
+	}
+	return time.Unix(0, 0)
+}
+
+// parseItunesDuration parses durations in the forms "SS", "MM:SS" and "HH:MM:SS"
+func parseItunesDuration(d string) float64 {
+	parts := strings.Split(strings.TrimSpace(d), ":")
+	seconds := 0.0
+	for _, p := range parts {
+		v, err := strconv.ParseFloat(p, 64)
+		if err != nil {
+			return 0
+		}
+		seconds = seconds*60 + v
+	}
+	return seconds
+}
+
+func splitKeywords(keywords string) []string {
+	var tags []string
+	for _, k := range strings.Split(keywords, ",") {
+		k = strings.TrimSpace(k)
+		if k != "" {
+			tags = append(tags, k)
+		}
+	}
+	return tags
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+func (i rssItem) toFeedItem() feedItem {
+	item := feedItem{
+		GUID:         firstNonEmpty(i.GUID, i.Link),
+		Title:        strings.TrimSpace(i.Title),
+		Description:  firstNonEmpty(i.Description, i.ItunesSummary),
+		Link:         strings.TrimSpace(i.Link),
+		PublishedAt:  parseFeedDate(i.PubDate),
+		Duration:     parseItunesDuration(i.ItunesDuration),
+		ThumbnailURL: firstNonEmpty(i.ItunesImage.Href, i.MediaThumbnail.URL),
+		Tags:         append(splitKeywords(i.ItunesKeywords), i.Categories...),
+		LicenseURL:   strings.TrimSpace(i.License),
+	}
+	enclosures := append(i.Enclosures, i.MediaContent...)
+	if len(enclosures) > 0 {
+		item.MediaURL = enclosures[0].URL
+		item.MediaSize = enclosures[0].Length
+		item.MediaType = enclosures[0].Type
+	}
+	return item
+}
+
+func (e atomEntry) toFeedItem() feedItem {
+	item := feedItem{
+		GUID:         strings.TrimSpace(e.ID),
+		Title:        strings.TrimSpace(e.Title),
+		Description:  firstNonEmpty(e.Summary, e.Content, e.MediaGroup.Description),
+		PublishedAt:  parseFeedDate(firstNonEmpty(e.Published, e.Updated)),
+		ThumbnailURL: e.MediaGroup.Thumbnail.URL,
+	}
+	for _, c := range e.Categories {
+		item.Tags = append(item.Tags, c.Term)
+	}
+	for _, l := range e.Links {
+		switch l.Rel {
+		case "enclosure":
+			if item.MediaURL == "" {
+				item.MediaURL = l.Href
+				item.MediaSize = l.Length
+				item.MediaType = l.Type
+			}
+		case "", "alternate":
+			if item.Link == "" {
+				item.Link = l.Href
+			}
+		case "license":
+			item.LicenseURL = l.Href
+		}
+	}
+	if item.MediaURL == "" && len(e.MediaGroup.Content) > 0 {
+		item.MediaURL = e.MediaGroup.Content[0].URL
+		item.MediaType = e.MediaGroup.Content[0].Type
+	}
+	if item.GUID == "" {
+		item.GUID = item.Link
+	}
+	return item
+}
+
+// parseFeed reads an RSS or Atom document and returns its channel information and items
+func parseFeed(raw []byte) (*ChannelInfo, []feedItem, error) {
+	var doc feedDocument
+	err := xml.Unmarshal(raw, &doc)
+	if err != nil {
+		return nil, nil, errors.Prefix("invalid feed", err)
+	}
+	var items []feedItem
+	switch strings.ToLower(doc.XMLName.Local) {
+	case "rss":
+		for _, i := range doc.Channel.Items {
+			item := i.toFeedItem()
+			if item.LicenseURL == "" {
+				item.LicenseURL = strings.TrimSpace(doc.Channel.License)
+			}
+			items = append(items, item)
+		}
+		channel := &ChannelInfo{
+			Title:        strings.TrimSpace(doc.Channel.Title),
+			Description:  firstNonEmpty(doc.Channel.Description, doc.Channel.ItunesSummary),
+			ThumbnailURL: firstNonEmpty(doc.Channel.ItunesImage.Href, doc.Channel.Image.URL),
+			Language:     strings.Split(strings.TrimSpace(doc.Channel.Language), "-")[0],
+		}
+		return channel, items, nil
+	case "feed":
+		for _, e := range doc.Entries {
+			items = append(items, e.toFeedItem())
+		}
+		channel := &ChannelInfo{
+			Title:        strings.TrimSpace(doc.Title),
+			Description:  strings.TrimSpace(doc.Subtitle),
+			ThumbnailURL: firstNonEmpty(doc.Logo, doc.Icon),
+		}
+		return channel, items, nil
+	}
+	return nil, nil, errors.Err("unsupported feed format: %s", doc.XMLName.Local)
+}
+
+// FeedSource syncs the items of an RSS/Atom feed, downloading their enclosures. It's mostly meant for podcasts.
+type FeedSource struct {
+	feedURL   string
+	awsConfig aws.Config
+	stopGroup *stop.Group
+}
+
+func NewFeedSource(feedURL string, awsConfig aws.Config, stopGroup *stop.Group) *FeedSource {
+	return &FeedSource{
+		feedURL:   feedURL,
+		awsConfig: awsConfig,
+		stopGroup: stopGroup,
+	}
+}
+
+func (f *FeedSource) fetch() (*ChannelInfo, []feedItem, error) {
+	res, err := http.Get(f.feedURL)
+	if err != nil {
+		return nil, nil, errors.Err(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, nil, errors.Err("error %d while fetching feed %s", res.StatusCode, f.feedURL)
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, errors.Err(err)
+	}
+	return parseFeed(body)
+}
+
+func (f *FeedSource) GetChannelInfo(channelID string) (*ChannelInfo, error) {
+	channel, _, err := f.fetch()
+	if err != nil {
+		return nil, err
+	}
+	if channel.ThumbnailURL == "" {
+		return nil, errors.Err("the feed %s has no image", f.feedURL)
+	}
+	return channel, nil
+}
+
+func (f *FeedSource) CountVideos(channelID string) (uint64, error) {
+	_, items, err := f.fetch()
+	if err != nil {
+		return 0, err
+	}
+	return uint64(len(items)), nil
+}
+
+func (f *FeedSource) ListVideos(channelID string, params ListParams) ([]Video, error) {
+	channel, items, err := f.fetch()
+	if err != nil {
+		return nil, err
+	}
+	// positions follow the youtube convention: the most recent video is at position 0
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].PublishedAt.After(items[j].PublishedAt)
+	})
+	videos := make([]Video, 0, len(items))
+	for i, item := range items {
+		if item.MediaURL == "" {
+			log.Debugf("feed item %s has no enclosure, skipping", item.GUID)
+			continue
+		}
+		if feedItemID(item) == "" {
+			log.Warnf("feed item %q can't be identified, skipping", item.Title)
+			continue
+		}
+		if item.ThumbnailURL == "" {
+			item.ThumbnailURL = channel.ThumbnailURL
+		}
+		item.ChannelName = channel.Title
+		videos = append(videos, newFeedVideo(params.VideoDir, item, i, channelID, f.awsConfig, f.stopGroup))
+	}
+	log.Infof("Got %d items from feed %s", len(videos), f.feedURL)
+	return videos, nil
+}
@@ -0,0 +1,260 @@
+package sources
+
+import (
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	"github.com/lbryio/lbry.go/v2/extras/jsonrpc"
+	"github.com/lbryio/lbry.go/v2/extras/stop"
+
+	"github.com/lbryio/ytsync/downloader"
+	"github.com/lbryio/ytsync/media"
+	"github.com/lbryio/ytsync/sdk"
+	"github.com/lbryio/ytsync/tags_manager"
+	"github.com/lbryio/ytsync/thumbs"
+
+	"github.com/aws/aws-sdk-go/aws"
+	log "github.com/sirupsen/logrus"
+)
+
+// FeedVideo is an item of an RSS/Atom feed whose media is the enclosure of the item
+type FeedVideo struct {
+	id               string
+	item             feedItem
+	playlistPosition int
+	dir              string
+	channelID        string
+	size             *int64
+	thumbnailURL     string
+	mediaInfo        *media.Info
+	awsConfig        aws.Config
+	stopGroup        *stop.Group
+	// descriptionTemplate renders claim descriptions, the default template is used when it's nil
+	descriptionTemplate *template.Template
+}
+
+func newFeedVideo(directory string, item feedItem, playlistPosition int, channelID string, awsConfig aws.Config, stopGroup *stop.Group) *FeedVideo {
+	return &FeedVideo{
+		id:               feedItemID(item),
+		item:             item,
+		playlistPosition: playlistPosition,
+		dir:              directory,
+		channelID:        channelID,
+		awsConfig:        awsConfig,
+		stopGroup:        stopGroup,
+	}
+}
+
+func (v *FeedVideo) ID() string {
+	return v.id
+}
+
+func (v *FeedVideo) PlaylistPosition() int {
+	return v.playlistPosition
+}
+
+func (v *FeedVideo) Title() string {
+	return v.item.Title
+}
+
+func (v *FeedVideo) IDAndNum() string {
+	return v.ID() + " (" + strconv.Itoa(v.playlistPosition) + " in feed)"
+}
+
+func (v *FeedVideo) PublishedAt() time.Time {
+	return v.item.PublishedAt
+}
+
+func (v *FeedVideo) Size() *int64 {
+	return v.size
+}
+
+// videoDir returns the directory the media of the item is downloaded to, the GUID can't be used as it is
+func (v *FeedVideo) videoDir() string {
+	return v.dir + "/" + feedFileName(v.id)
+}
+
+// getFullPath returns where the enclosure is stored, keeping the extension of the remote file so lbrynet can detect the media type
+func (v *FeedVideo) getFullPath() string {
+	ext := path.Ext(strings.SplitN(v.item.MediaURL, "?", 2)[0])
+	if ext == "" {
+		extensions, _ := mime.ExtensionsByType(v.item.MediaType)
+		if len(extensions) > 0 {
+			ext = extensions[0]
+		}
+	}
+	return v.videoDir() + "/" + feedFileName(v.id) + ext
+}
+
+func (v *FeedVideo) descriptionData() DescriptionData {
+	data := DescriptionData{
+		ID:              v.id,
+		Title:           v.item.Title,
+		Description:     abbreviateDescription(v.item.Description),
+		FullDescription: v.item.Description,
+		PublishedAt:     v.item.PublishedAt,
+		URL:             v.item.Link,
+		ChannelName:     v.item.ChannelName,
+		Duration:        time.Duration(v.item.Duration) * time.Second,
+	}
+	data.Chapters, _, _ = splitChapters(v.item.Description)
+	return data
+}
+
+// getAbbrevDescription renders the description template of the channel for the item
+func (v *FeedVideo) getAbbrevDescription() string {
+	return describe(v.descriptionTemplate, v.descriptionData())
+}
+
+func (v *FeedVideo) Metadata() Metadata {
+	tags, err := tags_manager.SanitizeTags(v.item.Tags, v.channelID)
+	if err != nil {
+		log.Errorln(err.Error())
+	}
+	return Metadata{
+		Title:        v.item.Title,
+		Description:  v.getAbbrevDescription(),
+		Tags:         tags,
+		ReleaseTime:  v.item.PublishedAt,
+		ThumbnailURL: v.thumbnailURL,
+		Media:        v.mediaInfo,
+		License:      sourceLicense("", v.item.LicenseURL),
+	}
+}
+
+func (v *FeedVideo) download(maxVideoSize int64) error {
+	err := os.MkdirAll(v.videoDir(), 0777)
+	if err != nil {
+		return errors.Err(err)
+	}
+	maxBytes := maxVideoSize * 1024 * 1024
+	if maxBytes > 0 && v.item.MediaSize > maxBytes {
+		return errors.Err(downloader.ErrTooBig)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-v.stopGroup.Ch():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	req, err := http.NewRequest(http.MethodGet, v.item.MediaURL, nil)
+	if err != nil {
+		return errors.Err(err)
+	}
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		if ctx.Err() != nil {
+			return errors.Err(downloader.ErrInterrupted)
+		}
+		return errors.Err(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return errors.Err("non 200 status code received: %d", res.StatusCode)
+	}
+	if maxBytes > 0 && res.ContentLength > maxBytes {
+		return errors.Err(downloader.ErrTooBig)
+	}
+
+	out, err := os.Create(v.getFullPath())
+	if err != nil {
+		return errors.Err(err)
+	}
+	defer out.Close()
+	var body io.Reader = res.Body
+	if maxBytes > 0 {
+		body = io.LimitReader(res.Body, maxBytes+1)
+	}
+	written, err := io.Copy(out, body)
+	if err != nil {
+		if ctx.Err() != nil {
+			return errors.Err(downloader.ErrInterrupted)
+		}
+		return errors.Err(err)
+	}
+	if maxBytes > 0 && written > maxBytes {
+		return errors.Err(downloader.ErrTooBig)
+	}
+	if written == 0 {
+		return errors.Err("Error in daemon: Cannot publish empty file")
+	}
+	v.size = &written
+	return nil
+}
+
+func (v *FeedVideo) delete(reason string) {
+	if v.id == "" {
+		// the directory of the video would be the one of all the videos
+		log.Errorln("refusing to delete the media of a feed item without an ID")
+		return
+	}
+	err := os.RemoveAll(v.videoDir())
+	if err != nil {
+		log.Errorln(errors.Prefix("delete error", err))
+		return
+	}
+	log.Debugf("%s deleted from disk for '%s'", v.id, reason)
+}
+
+func (v *FeedVideo) Sync(daemon *jsonrpc.Client, params SyncParams, existingVideoData *sdk.SyncedVideo, reprocess bool, walletLock *sync.RWMutex) (*SyncSummary, error) {
+	if reprocess && existingVideoData != nil && existingVideoData.Published {
+		return nil, errors.Prefix("upgrade failed", errors.Err("metadata upgrades are not supported for feed items"))
+	}
+	if params.MaxVideoLength > 0 && v.item.Duration > params.MaxVideoLength*3600 {
+		return nil, errors.Err(downloader.ErrTooLong)
+	}
+	v.descriptionTemplate = params.DescriptionTemplate
+
+	err := v.download(int64(params.MaxVideoSize))
+	//delete the media in all cases (and ignore the error)
+	defer v.delete("finished download and publish")
+	if err != nil {
+		return nil, errors.Prefix("download error", err)
+	}
+	log.Debugln("Downloaded " + v.id)
+
+	v.mediaInfo, err = probeMedia(v.getFullPath(), media.Expectations{
+		Duration:     v.item.Duration,
+		RequireVideo: strings.HasPrefix(v.item.MediaType, "video/"),
+		RequireAudio: true,
+	})
+	if err != nil {
+		return nil, errors.Prefix("probe error", err)
+	}
+	publishPath := v.getFullPath()
+	transcoded, err := transcodeIfNeeded(publishPath, v.videoDir()+"/"+feedFileName(v.id)+".transcoded.mp4", v.mediaInfo, params, v.stopGroup.Ch())
+	if err != nil {
+		return nil, errors.Prefix("transcode error", err)
+	}
+	if transcoded != nil {
+		publishPath = transcoded.path
+		v.mediaInfo = transcoded.info
+		v.size = &transcoded.size
+	}
+
+	if v.item.ThumbnailURL == "" {
+		return nil, errors.Prefix("thumbnail error", errors.Err("no thumbnail available for %s", v.id))
+	}
+	v.thumbnailURL, err = thumbs.MirrorThumbnail(v.item.ThumbnailURL, v.id, v.awsConfig)
+	if err != nil {
+		return nil, errors.Prefix("thumbnail error", err)
+	}
+	log.Debugln("Created thumbnail for " + v.id)
+
+	summary, err := publishVideo(daemon, v.Metadata(), publishPath, params, walletLock)
+	return summary, errors.Prefix("publish error", err)
+}
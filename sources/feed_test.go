@@ -0,0 +1,130 @@
+package sources
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+const testRSS = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd" xmlns:creativeCommons="http://backend.userland.com/creativeCommonsRssModule">
+<channel>
+	<title>Test Podcast</title>
+	<description>A podcast about tests</description>
+	<language>en-us</language>
+	<itunes:image href="https://example.com/cover.jpg"/>
+	<creativeCommons:license>https://creativecommons.org/licenses/by-sa/4.0/</creativeCommons:license>
+	<item>
+		<title>Episode 1</title>
+		<guid isPermaLink="false">https://example.com/?p=123</guid>
+		<pubDate>Tue, 03 Dec 2019 10:00:00 +0000</pubDate>
+		<enclosure url="https://example.com/ep1.mp3" length="1234" type="audio/mpeg"/>
+		<itunes:duration>01:02:03</itunes:duration>
+		<itunes:summary>First episode</itunes:summary>
+		<itunes:keywords>testing, go</itunes:keywords>
+	</item>
+</channel>
+</rss>`
+
+const testAtom = `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xmlns:media="http://search.yahoo.com/mrss/">
+	<title>Test Feed</title>
+	<logo>https://example.com/logo.png</logo>
+	<entry>
+		<id>urn:uuid:1225c695-cfb8-4ebb-aaaa-80da344efa6a</id>
+		<title>Entry 1</title>
+		<published>2019-12-03T10:00:00Z</published>
+		<link rel="alternate" href="https://example.com/entry1"/>
+		<link rel="enclosure" href="https://example.com/entry1.mp4" length="5678" type="video/mp4"/>
+		<link rel="license" href="https://example.com/license"/>
+		<media:group>
+			<media:description>Entry description</media:description>
+			<media:thumbnail url="https://example.com/entry1.jpg"/>
+		</media:group>
+	</entry>
+</feed>`
+
+func TestParseRSSFeed(t *testing.T) {
+	channel, items, err := parseFeed([]byte(testRSS))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if channel.Title != "Test Podcast" || channel.ThumbnailURL != "https://example.com/cover.jpg" || channel.Language != "en" {
+		t.Errorf("unexpected channel info: %+v", channel)
+	}
+	if len(items) != 1 {
+		t.Fatalf("expected 1 item, got %d", len(items))
+	}
+	item := items[0]
+	if item.MediaURL != "https://example.com/ep1.mp3" || item.MediaSize != 1234 {
+		t.Errorf("unexpected enclosure: %s (%d)", item.MediaURL, item.MediaSize)
+	}
+	if item.Duration != 3723 {
+		t.Errorf("expected a duration of 3723 seconds, got %f", item.Duration)
+	}
+	if !item.PublishedAt.Equal(time.Date(2019, 12, 3, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected publishing date: %s", item.PublishedAt)
+	}
+	if item.Description != "First episode" || len(item.Tags) != 2 {
+		t.Errorf("unexpected description or tags: %s %v", item.Description, item.Tags)
+	}
+	if item.LicenseURL != "https://creativecommons.org/licenses/by-sa/4.0/" {
+		t.Errorf("the items must be released under the license of the feed, got %q", item.LicenseURL)
+	}
+	if id := feedItemID(item); id != "https://example.com/?p=123" {
+		t.Errorf("the GUID must be the video ID, got %s", id)
+	}
+}
+
+func TestParseAtomFeed(t *testing.T) {
+	channel, items, err := parseFeed([]byte(testAtom))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if channel.Title != "Test Feed" || channel.ThumbnailURL != "https://example.com/logo.png" {
+		t.Errorf("unexpected channel info: %+v", channel)
+	}
+	if len(items) != 1 {
+		t.Fatalf("expected 1 item, got %d", len(items))
+	}
+	item := items[0]
+	if item.MediaURL != "https://example.com/entry1.mp4" || item.Link != "https://example.com/entry1" {
+		t.Errorf("unexpected links: %s %s", item.MediaURL, item.Link)
+	}
+	if item.ThumbnailURL != "https://example.com/entry1.jpg" || item.Description != "Entry description" {
+		t.Errorf("unexpected media group: %s %s", item.ThumbnailURL, item.Description)
+	}
+	if item.LicenseURL != "https://example.com/license" {
+		t.Errorf("unexpected license: %q", item.LicenseURL)
+	}
+}
+
+func TestFeedItemID(t *testing.T) {
+	for _, tt := range []struct {
+		item feedItem
+		id   string
+	}{
+		{feedItem{GUID: " https://example.com/?p=1 ", Link: "https://example.com/ep1"}, "https://example.com/?p=1"},
+		{feedItem{Link: "https://example.com/ep1", MediaURL: "https://example.com/ep1.mp3"}, "https://example.com/ep1"},
+		{feedItem{MediaURL: "https://example.com/ep1.mp3"}, "https://example.com/ep1.mp3"},
+		{feedItem{Title: "no identity"}, ""},
+	} {
+		if id := feedItemID(tt.item); id != tt.id {
+			t.Errorf("%+v: expected the ID %q, got %q", tt.item, tt.id, id)
+		}
+	}
+}
+
+func TestFeedFileName(t *testing.T) {
+	names := make(map[string]string)
+	for _, id := range []string{"https://example.com/?p=1", "https://example.com/_p_1", "https_example_com_p_1", "?!", "../.."} {
+		name := feedFileName(id)
+		if !regexp.MustCompile(`^[0-9a-f]{32}$`).MatchString(name) {
+			t.Errorf("%s: invalid file name %q", id, name)
+		}
+		if other, ok := names[name]; ok {
+			t.Errorf("%s collides with %s", id, other)
+		}
+		names[name] = id
+	}
+}
@@ -0,0 +1,70 @@
+package sources
+
+import (
+	"net/url"
+	"strings"
+)
+
+// License is the license a claim is published under
+type License struct {
+	Name string
+	URL  string
+}
+
+// DefaultLicense is used when the license of a video is unknown
+var DefaultLicense = License{Name: "Copyrighted (contact publisher)"}
+
+// youtubeCreativeCommons is the only Creative Commons license youtube offers to uploaders
+var youtubeCreativeCommons = License{
+	Name: "Creative Commons Attribution 3.0 Unported",
+	URL:  "https://creativecommons.org/licenses/by/3.0/legalcode",
+}
+
+// creativeCommonsNames are the names of the Creative Commons licenses by the code in their URL
+var creativeCommonsNames = map[string]string{
+	"by":       "Attribution",
+	"by-sa":    "Attribution-ShareAlike",
+	"by-nd":    "Attribution-NoDerivatives",
+	"by-nc":    "Attribution-NonCommercial",
+	"by-nc-sa": "Attribution-NonCommercial-ShareAlike",
+	"by-nc-nd": "Attribution-NonCommercial-NoDerivatives",
+}
+
+// sourceLicense maps the license announced by a source, by name and/or URL, to the license of the claim. Creative
+// Commons licenses are named after their URL, the other licenses are kept as they are. The license is empty if the
+// source announces none
+func sourceLicense(name string, licenseURL string) License {
+	name, licenseURL = strings.TrimSpace(name), strings.TrimSpace(licenseURL)
+	// youtube-dl names the license of youtube videos rather than linking to it
+	if licenseURL == "" && strings.HasPrefix(strings.ToLower(name), "creative commons attribution license") {
+		return youtubeCreativeCommons
+	}
+	if licenseURL == "" && strings.HasPrefix(strings.ToLower(name), "standard youtube license") {
+		return License{}
+	}
+	if u, err := url.Parse(licenseURL); err == nil && strings.HasSuffix(u.Host, "creativecommons.org") {
+		parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+		if len(parts) >= 3 && parts[0] == "licenses" && creativeCommonsNames[parts[1]] != "" {
+			return License{Name: "Creative Commons " + creativeCommonsNames[parts[1]] + " " + parts[2], URL: licenseURL}
+		}
+		if len(parts) >= 3 && parts[0] == "publicdomain" && parts[1] == "zero" {
+			return License{Name: "CC0 " + parts[2] + " Universal", URL: licenseURL}
+		}
+	}
+	if name == "" {
+		name = licenseURL
+	}
+	return License{Name: name, URL: licenseURL}
+}
+
+// claimLicense returns the license a video is published under: the license of the channel when it's set, otherwise
+// the license of the video on the source or DefaultLicense if it's unknown
+func claimLicense(license License, params SyncParams) License {
+	if params.License != nil && params.License.Name != "" {
+		return *params.License
+	}
+	if license.Name == "" {
+		return DefaultLicense
+	}
+	return license
+}
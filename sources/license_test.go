@@ -0,0 +1,32 @@
+package sources
+
+import "testing"
+
+func TestSourceLicense(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		url      string
+		expected License
+	}{
+		{"", "", License{}},
+		{"Creative Commons Attribution license (reuse allowed)", "", youtubeCreativeCommons},
+		{"", "https://creativecommons.org/licenses/by-nc-sa/4.0/", License{Name: "Creative Commons Attribution-NonCommercial-ShareAlike 4.0", URL: "https://creativecommons.org/licenses/by-nc-sa/4.0/"}},
+		{"", "http://creativecommons.org/publicdomain/zero/1.0/", License{Name: "CC0 1.0 Universal", URL: "http://creativecommons.org/publicdomain/zero/1.0/"}},
+		{"", "https://example.com/license", License{Name: "https://example.com/license", URL: "https://example.com/license"}},
+		{"Standard YouTube License", "", License{}},
+		{"GPL", "", License{Name: "GPL"}},
+	} {
+		if license := sourceLicense(c.name, c.url); license != c.expected {
+			t.Errorf("%q %q: expected %+v, got %+v", c.name, c.url, c.expected, license)
+		}
+	}
+
+	params := SyncParams{}
+	if license := claimLicense(sourceLicense("", ""), params); license != DefaultLicense {
+		t.Errorf("unknown licenses must default to %+v, got %+v", DefaultLicense, license)
+	}
+	params.License = &License{Name: "Public Domain"}
+	if license := claimLicense(youtubeCreativeCommons, params); license != *params.License {
+		t.Errorf("the license of the channel must win, got %+v", license)
+	}
+}
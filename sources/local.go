@@ -0,0 +1,194 @@
+package sources
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	"github.com/lbryio/lbry.go/v2/extras/stop"
+
+	"github.com/aws/aws-sdk-go/aws"
+	log "github.com/sirupsen/logrus"
+)
+
+var mediaExtensions = []string{".mp4", ".mkv", ".webm", ".mov", ".m4v", ".m4a", ".mp3", ".ogg", ".opus", ".flac"}
+var imageExtensions = []string{".jpg", ".jpeg", ".png", ".webp"}
+
+// LocalSource syncs media files that were already downloaded to disk along with their youtube-dl .info.json sidecar
+// files. Videos of a channel are looked up in <root>/<channel id> and its subdirectories if such directory exists,
+// among the files directly in <root> otherwise.
+type LocalSource struct {
+	root      string
+	awsConfig aws.Config
+	stopGroup *stop.Group
+}
+
+func NewLocalSource(root string, awsConfig aws.Config, stopGroup *stop.Group) *LocalSource {
+	return &LocalSource{
+		root:      root,
+		awsConfig: awsConfig,
+		stopGroup: stopGroup,
+	}
+}
+
+// channelDir returns the directory of the channel and whether it's the channel's own directory, in which case its
+// subdirectories belong to the channel too
+func (l *LocalSource) channelDir(channelID string) (string, bool) {
+	dir := filepath.Join(l.root, channelID)
+	if fi, err := os.Stat(dir); err == nil && fi.IsDir() {
+		return dir, true
+	}
+	return l.root, false
+}
+
+// findWithExtension returns the first existing file named base + one of the given extensions
+func findWithExtension(base string, extensions []string) string {
+	for _, ext := range extensions {
+		if _, err := os.Stat(base + ext); err == nil {
+			return base + ext
+		}
+	}
+	return ""
+}
+
+// findInfoFiles walks the channel directory and returns all the video and playlist info files in it
+func (l *LocalSource) findInfoFiles(channelID string) (videos []string, playlists []string, err error) {
+	dir, own := l.channelDir(channelID)
+	err = filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			// the subdirectories of the root can be the directories of other channels
+			if !own && path != dir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(fi.Name(), ".info.json") {
+			return nil
+		}
+		info, err := loadYtdlInfo(path)
+		if err != nil {
+			log.Errorln(err.Error())
+			return nil
+		}
+		if info.Type == "playlist" {
+			playlists = append(playlists, path)
+		} else {
+			videos = append(videos, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, errors.Err(err)
+	}
+	return videos, playlists, nil
+}
+
+func (l *LocalSource) GetChannelInfo(channelID string) (*ChannelInfo, error) {
+	videos, playlists, err := l.findInfoFiles(channelID)
+	if err != nil {
+		return nil, err
+	}
+	var info *ytdlInfo
+	if len(playlists) > 0 {
+		info, err = loadYtdlInfo(playlists[0])
+	} else if len(videos) > 0 {
+		info, err = loadYtdlInfo(videos[0])
+	} else {
+		return nil, errors.Err("no info files found for channel %s", channelID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	channelInfo := &ChannelInfo{
+		Title:    info.Channel,
+		Language: info.Language,
+	}
+	if channelInfo.Title == "" {
+		channelInfo.Title = info.Uploader
+	}
+	if info.Type == "playlist" {
+		channelInfo.Description = info.Description
+		channelInfo.ThumbnailURL = info.bestThumbnail()
+	}
+	dir, _ := l.channelDir(channelID)
+	if thumbnail := findWithExtension(filepath.Join(dir, "channel"), imageExtensions); thumbnail != "" {
+		channelInfo.ThumbnailURL = thumbnail
+	}
+	if channelInfo.ThumbnailURL == "" {
+		return nil, errors.Err("no channel thumbnail found for channel %s", channelID)
+	}
+	return channelInfo, nil
+}
+
+func (l *LocalSource) CountVideos(channelID string) (uint64, error) {
+	videos, err := l.ListVideos(channelID, ListParams{})
+	if err != nil {
+		return 0, err
+	}
+	return uint64(len(videos)), nil
+}
+
+func (l *LocalSource) ListVideos(channelID string, params ListParams) ([]Video, error) {
+	infoFiles, _, err := l.findInfoFiles(channelID)
+	if err != nil {
+		return nil, err
+	}
+	localVideos := make([]*LocalVideo, 0, len(infoFiles))
+	for _, infoFile := range infoFiles {
+		info, err := loadYtdlInfo(infoFile)
+		if err != nil {
+			return nil, err
+		}
+		base := strings.TrimSuffix(infoFile, ".info.json")
+		mediaPath := findWithExtension(base, mediaExtensions)
+		if mediaPath == "" {
+			log.Warnf("no media file found for %s, skipping", infoFile)
+			continue
+		}
+		if info.ID == "" {
+			info.ID = filepath.Base(base)
+		}
+		localVideos = append(localVideos, newLocalVideo(params.VideoDir, info, mediaPath, findWithExtension(base, imageExtensions), channelID, l.awsConfig, l.stopGroup))
+	}
+
+	// positions follow the youtube convention: the most recent video is at position 0
+	sort.Slice(localVideos, func(i, j int) bool {
+		return localVideos[i].PublishedAt().After(localVideos[j].PublishedAt())
+	})
+	onDisk := make(map[string]bool, len(localVideos))
+	for i, v := range localVideos {
+		v.playlistPosition = i
+		onDisk[v.ID()] = true
+	}
+	if params.VideosLimit > 0 && len(localVideos) > params.VideosLimit {
+		localVideos = localVideos[:params.VideosLimit]
+	}
+	listed := make(map[string]bool, len(localVideos))
+	videos := make([]Video, 0, len(localVideos))
+	for _, v := range localVideos {
+		listed[v.ID()] = true
+		videos = append(videos, v)
+	}
+	log.Infof("Found %d local videos for channel %s", len(onDisk), channelID)
+
+	removed := 0
+	for id, sv := range params.SyncedVideos {
+		if !sv.Published || listed[id] {
+			continue
+		}
+		if !onDisk[id] {
+			removed++
+		}
+		videos = append(videos, newMockedLocalVideo(params.VideoDir, id, channelID, !onDisk[id], l.awsConfig, l.stopGroup))
+	}
+	if removed > 0 {
+		log.Infof("%d published videos were removed from the local directory", removed)
+	}
+	return videos, nil
+}
@@ -0,0 +1,211 @@
+package sources
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	"github.com/lbryio/lbry.go/v2/extras/jsonrpc"
+	"github.com/lbryio/lbry.go/v2/extras/stop"
+
+	"github.com/lbryio/ytsync/downloader"
+	"github.com/lbryio/ytsync/media"
+	"github.com/lbryio/ytsync/sdk"
+	"github.com/lbryio/ytsync/tags_manager"
+	"github.com/lbryio/ytsync/thumbs"
+
+	"github.com/aws/aws-sdk-go/aws"
+	log "github.com/sirupsen/logrus"
+)
+
+// LocalVideo is a media file on disk described by a youtube-dl .info.json file
+type LocalVideo struct {
+	info             *ytdlInfo
+	path             string
+	thumbnailPath    string
+	channelID        string
+	playlistPosition int
+	size             *int64
+	awsConfig        aws.Config
+	thumbnailURL     string
+	mediaInfo        *media.Info
+	// dir is where transcoded copies are written, the original files are never touched
+	dir       string
+	stopGroup *stop.Group
+	// descriptionTemplate renders claim descriptions, the default template is used when it's nil
+	descriptionTemplate *template.Template
+	// mocked videos are published videos that weren't listed, removed is set when their media is gone
+	mocked  bool
+	removed bool
+}
+
+func newLocalVideo(directory string, info *ytdlInfo, path string, thumbnailPath string, channelID string, awsConfig aws.Config, stopGroup *stop.Group) *LocalVideo {
+	return &LocalVideo{
+		dir:           directory,
+		info:          info,
+		path:          path,
+		thumbnailPath: thumbnailPath,
+		channelID:     channelID,
+		awsConfig:     awsConfig,
+		stopGroup:     stopGroup,
+	}
+}
+
+// newMockedLocalVideo creates a placeholder for a published video that isn't listed
+func newMockedLocalVideo(directory string, videoID string, channelID string, removed bool, awsConfig aws.Config, stopGroup *stop.Group) *LocalVideo {
+	return &LocalVideo{
+		dir:       directory,
+		info:      &ytdlInfo{ID: videoID},
+		channelID: channelID,
+		awsConfig: awsConfig,
+		stopGroup: stopGroup,
+		mocked:    true,
+		removed:   removed,
+	}
+}
+
+func (v *LocalVideo) ID() string {
+	return v.info.ID
+}
+
+func (v *LocalVideo) PlaylistPosition() int {
+	return v.playlistPosition
+}
+
+func (v *LocalVideo) Title() string {
+	return v.info.Title
+}
+
+func (v *LocalVideo) IDAndNum() string {
+	return v.ID() + " (" + strconv.Itoa(v.playlistPosition) + " in channel)"
+}
+
+func (v *LocalVideo) PublishedAt() time.Time {
+	return v.info.releaseTime()
+}
+
+func (v *LocalVideo) Size() *int64 {
+	return v.size
+}
+
+func (v *LocalVideo) RemovedAtSource() bool {
+	return v.removed
+}
+
+func (v *LocalVideo) descriptionData() DescriptionData {
+	data := DescriptionData{
+		ID:              v.ID(),
+		Title:           v.info.Title,
+		Description:     abbreviateDescription(v.info.Description),
+		FullDescription: v.info.Description,
+		PublishedAt:     v.PublishedAt(),
+		URL:             v.info.WebpageURL,
+		ChannelName:     firstNonEmpty(v.info.Channel, v.info.Uploader),
+		Duration:        time.Duration(v.info.Duration) * time.Second,
+	}
+	data.Chapters, _, _ = splitChapters(v.info.Description)
+	return data
+}
+
+// getAbbrevDescription renders the description template of the channel for the video
+func (v *LocalVideo) getAbbrevDescription() string {
+	return describe(v.descriptionTemplate, v.descriptionData())
+}
+
+func (v *LocalVideo) Metadata() Metadata {
+	tags, err := tags_manager.SanitizeTags(v.info.Tags, v.channelID)
+	if err != nil {
+		log.Errorln(err.Error())
+	}
+	for _, c := range v.info.Categories {
+		tags = append(tags, strings.ToLower(c))
+	}
+	var languages []string
+	if v.info.Language != "" {
+		languages = []string{v.info.Language}
+	}
+	return Metadata{
+		Title:        v.info.Title,
+		Description:  v.getAbbrevDescription(),
+		Tags:         tags,
+		Languages:    languages,
+		ReleaseTime:  v.PublishedAt(),
+		ThumbnailURL: v.thumbnailURL,
+		Media:        v.mediaInfo,
+		License:      sourceLicense(v.info.License, ""),
+	}
+}
+
+func (v *LocalVideo) triggerThumbnailSave() (err error) {
+	thumbnail := v.thumbnailPath
+	if thumbnail == "" {
+		thumbnail = v.info.bestThumbnail()
+	}
+	if thumbnail == "" {
+		return errors.Err("no thumbnail available for %s", v.ID())
+	}
+	v.thumbnailURL, err = thumbs.MirrorThumbnail(thumbnail, v.ID(), v.awsConfig)
+	return err
+}
+
+func (v *LocalVideo) Sync(daemon *jsonrpc.Client, params SyncParams, existingVideoData *sdk.SyncedVideo, reprocess bool, walletLock *sync.RWMutex) (*SyncSummary, error) {
+	if reprocess && existingVideoData != nil && existingVideoData.Published {
+		return nil, errors.Prefix("upgrade failed", errors.Err("metadata upgrades are not supported for local videos"))
+	}
+	if v.mocked {
+		return nil, errors.Err("%s is not in the local directory anymore", v.ID())
+	}
+	v.descriptionTemplate = params.DescriptionTemplate
+	fi, err := os.Stat(v.path)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	videoSize := fi.Size()
+	v.size = &videoSize
+	if params.MaxVideoSize > 0 && videoSize > int64(params.MaxVideoSize)*1024*1024 {
+		return nil, errors.Err(downloader.ErrTooBig)
+	}
+	if params.MaxVideoLength > 0 && v.info.Duration > params.MaxVideoLength*3600 {
+		return nil, errors.Err(downloader.ErrTooLong)
+	}
+
+	v.mediaInfo, err = probeMedia(v.path, media.Expectations{
+		Duration: v.info.Duration,
+		// youtube-dl only reports dimensions for formats with a video stream
+		RequireVideo: v.info.Width > 0 || v.info.Height > 0,
+		RequireAudio: true,
+	})
+	if err != nil {
+		return nil, errors.Prefix("probe error", err)
+	}
+	publishPath := v.path
+	transcoded, err := transcodeIfNeeded(v.path, v.dir+"/"+v.ID()+".transcoded.mp4", v.mediaInfo, params, v.stopGroup.Ch())
+	if err != nil {
+		return nil, errors.Prefix("transcode error", err)
+	}
+	if transcoded != nil {
+		publishPath = transcoded.path
+		v.mediaInfo = transcoded.info
+		v.size = &transcoded.size
+		defer func() {
+			err := os.Remove(transcoded.path)
+			if err != nil {
+				log.Errorln(errors.Prefix("delete error", err))
+			}
+		}()
+	}
+
+	err = v.triggerThumbnailSave()
+	if err != nil {
+		return nil, errors.Prefix("thumbnail error", err)
+	}
+	log.Debugln("Created thumbnail for " + v.ID())
+
+	// unlike downloaded videos, local files are never deleted after publishing: they're the archive
+	summary, err := publishVideo(daemon, v.Metadata(), publishPath, params, walletLock)
+	return summary, errors.Prefix("publish error", err)
+}
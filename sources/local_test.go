@@ -0,0 +1,96 @@
+package sources
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lbryio/lbry.go/v2/extras/stop"
+
+	"github.com/lbryio/ytsync/sdk"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+func TestLocalSourceListVideos(t *testing.T) {
+	root, err := ioutil.TempDir("", "ytsync-local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	channelDir := filepath.Join(root, "UCtest")
+	err = os.MkdirAll(filepath.Join(channelDir, "nested"), 0777)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"old.info.json":        `{"id": "old", "title": "Old video", "upload_date": "20150101"}`,
+		"old.mp4":              "",
+		"nested/new.info.json": `{"id": "new", "title": "New video", "timestamp": 1577836800}`,
+		"nested/new.mkv":       "",
+		"missing.info.json":    `{"id": "missing", "title": "Media was never downloaded"}`,
+		"channel.info.json":    `{"_type": "playlist", "id": "UCtest", "channel": "Test channel"}`,
+		"channel.jpg":          "",
+	}
+	for name, content := range files {
+		err = ioutil.WriteFile(filepath.Join(channelDir, name), []byte(content), 0666)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	source := NewLocalSource(root, aws.Config{}, stop.New())
+	videos, err := source.ListVideos("UCtest", ListParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(videos) != 2 {
+		t.Fatalf("expected 2 videos, got %d", len(videos))
+	}
+	if videos[0].ID() != "new" || videos[0].PlaylistPosition() != 0 {
+		t.Errorf("expected the newest video first, got %s at position %d", videos[0].ID(), videos[0].PlaylistPosition())
+	}
+	if videos[1].ID() != "old" || videos[1].PlaylistPosition() != 1 {
+		t.Errorf("expected the oldest video last, got %s at position %d", videos[1].ID(), videos[1].PlaylistPosition())
+	}
+
+	videos, err = source.ListVideos("UCtest", ListParams{
+		VideosLimit:  1,
+		SyncedVideos: map[string]sdk.SyncedVideo{"old": {Published: true}, "gone": {Published: true}, "failed": {}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed := make(map[string]bool)
+	for _, v := range videos[1:] {
+		removed[v.ID()] = v.(RemovableVideo).RemovedAtSource()
+	}
+	if len(videos) != 3 || videos[0].ID() != "new" || len(removed) != 2 || removed["old"] || !removed["gone"] {
+		t.Errorf("expected the limited listing and the published videos that weren't listed, got %d videos: %v", len(videos), removed)
+	}
+
+	// without a directory of its own, a channel doesn't get the videos of the other channels
+	err = ioutil.WriteFile(filepath.Join(root, "top.info.json"), []byte(`{"id": "top", "title": "Top video"}`), 0666)
+	if err == nil {
+		err = ioutil.WriteFile(filepath.Join(root, "top.mp4"), nil, 0666)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	videos, err = source.ListVideos("UCother", ListParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(videos) != 1 || videos[0].ID() != "top" {
+		t.Errorf("expected only the top level video, got %d videos", len(videos))
+	}
+
+	info, err := source.GetChannelInfo("UCtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Title != "Test channel" || info.ThumbnailURL != filepath.Join(channelDir, "channel.jpg") {
+		t.Errorf("unexpected channel info: %+v", info)
+	}
+}
@@ -1,23 +1,183 @@
 package sources
 
 import (
+	"math"
+	"os"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/lbryio/lbry.go/v2/extras/errors"
 	"github.com/lbryio/lbry.go/v2/extras/jsonrpc"
+	"github.com/lbryio/lbry.go/v2/extras/util"
+	"github.com/lbryio/ytsync/media"
 	"github.com/lbryio/ytsync/namer"
+
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
 )
 
 type SyncSummary struct {
 	ClaimID   string
 	ClaimName string
+	// CaptionLanguages are the languages of the captions embedded in the published file
+	CaptionLanguages []string
+}
+
+// Metadata holds the source independent information required to publish a video
+type Metadata struct {
+	Title        string
+	Description  string
+	Tags         []string
+	Languages    []string
+	Locations    []jsonrpc.Location
+	ReleaseTime  time.Time
+	ThumbnailURL string
+	// Media is the result of probing the file, it's nil if the file wasn't probed
+	Media *media.Info
+	// License is the license of the video on the source, it's empty if it's unknown
+	License License
+}
+
+// probeMedia inspects the file about to be published and makes sure it's sane before spending credits on it
+func probeMedia(path string, expectations media.Expectations) (*media.Info, error) {
+	info, err := media.Probe(path)
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("probed %s: %dx%d, %.0fs, video: %s, audio: %s, %d bps", path, info.Width, info.Height, info.Duration, info.VideoCodec, info.AudioCodec, info.Bitrate)
+	err = info.Check(expectations)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
 }
 
-func publishAndRetryExistingNames(daemon *jsonrpc.Client, title, filename string, amount float64, options jsonrpc.StreamCreateOptions, namer *namer.Namer, walletLock *sync.RWMutex) (*SyncSummary, error) {
+// transcodeResult describes a file produced by transcodeIfNeeded
+type transcodeResult struct {
+	path string
+	info *media.Info
+	size int64
+}
+
+// transcodeIfNeeded re-encodes the file at path into output when it doesn't fit the transcoding profile in params.
+// A nil result means the original file can be published as it is.
+func transcodeIfNeeded(path string, output string, info *media.Info, params SyncParams, stop <-chan struct{}) (*transcodeResult, error) {
+	profile := params.TranscodeProfile
+	if profile == nil || info == nil || !profile.Needed(info) {
+		return nil, nil
+	}
+	start := time.Now()
+	err := media.Transcode(path, output, info, *profile, stop)
+	if err != nil {
+		return nil, err
+	}
+	transcodedInfo, err := media.Probe(output)
+	if err != nil {
+		return nil, err
+	}
+	original, err := os.Stat(path)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	transcoded, err := os.Stat(output)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	saved := 100 * (1 - float64(transcoded.Size())/float64(original.Size()))
+	log.Infof("transcoded %s to %s in %s: %s/%s %dp -> %s/%s %dp, %.1fMB -> %.1fMB (%.1f%% saved)",
+		path, profile.Name, time.Since(start).Round(time.Second),
+		info.VideoCodec, info.AudioCodec, info.Height,
+		transcodedInfo.VideoCodec, transcodedInfo.AudioCodec, transcodedInfo.Height,
+		float64(original.Size())/1024/1024, float64(transcoded.Size())/1024/1024, saved)
+	return &transcodeResult{
+		path: output,
+		info: transcodedInfo,
+		size: transcoded.Size(),
+	}, nil
+}
+
+// appendTags adds the extra tags that aren't in tags yet
+func appendTags(tags []string, extra []string) []string {
+	for _, t := range extra {
+		if !util.InSlice(t, tags) {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+func getFee(params SyncParams) (*jsonrpc.Fee, error) {
+	if params.Fee == nil {
+		return nil, nil
+	}
+	feeAmount, err := decimal.NewFromString(params.Fee.Amount)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	return &jsonrpc.Fee{
+		FeeAddress:  &params.Fee.Address,
+		FeeAmount:   feeAmount,
+		FeeCurrency: jsonrpc.Currency(params.Fee.Currency),
+	}, nil
+}
+
+// publishVideo publishes the file at filename as a stream in the channel set in params
+func publishVideo(daemon *jsonrpc.Client, metadata Metadata, filename string, params SyncParams, walletLock *sync.RWMutex) (*SyncSummary, error) {
+	fee, err := getFee(params)
+	if err != nil {
+		return nil, err
+	}
+	license := claimLicense(metadata.License, params)
+	options := jsonrpc.StreamCreateOptions{
+		ClaimCreateOptions: jsonrpc.ClaimCreateOptions{
+			Title:        &metadata.Title,
+			Description:  &metadata.Description,
+			ClaimAddress: &params.ClaimAddress,
+			Languages:    metadata.Languages,
+			ThumbnailURL: &metadata.ThumbnailURL,
+			Tags:         appendTags(metadata.Tags, params.Tags),
+			Locations:    metadata.Locations,
+			FundingAccountIDs: []string{
+				params.DefaultAccount,
+			},
+		},
+		Fee:         fee,
+		License:     &license.Name,
+		ReleaseTime: util.PtrToInt64(metadata.ReleaseTime.Unix()),
+		ChannelID:   &params.ChannelID,
+	}
+	if license.URL != "" {
+		options.LicenseURL = &license.URL
+	}
+	if metadata.Media != nil {
+		options.Duration = util.PtrToUint64(uint64(math.Ceil(metadata.Media.Duration)))
+		if metadata.Media.HasVideo() {
+			options.Width = util.PtrToUint(metadata.Media.Width)
+			options.Height = util.PtrToUint(metadata.Media.Height)
+		}
+	}
+	summary, err := publishAndRetryExistingNames(daemon, metadata.Title, filename, params.Amount, options, params.Namer, params.Journal, walletLock)
+	if err != nil {
+		return nil, err
+	}
+	if metadata.Media != nil {
+		summary.CaptionLanguages = metadata.Media.SubtitleLanguages
+	}
+	return summary, nil
+}
+
+func publishAndRetryExistingNames(daemon *jsonrpc.Client, title, filename string, amount float64, options jsonrpc.StreamCreateOptions, namer *namer.Namer, journal PublishJournal, walletLock *sync.RWMutex) (*SyncSummary, error) {
 	walletLock.RLock()
 	defer walletLock.RUnlock()
 	for {
 		name := namer.GetNextName(title)
+		if journal != nil {
+			err := journal.Intent(name)
+			if err != nil {
+				return nil, errors.Prefix("could not journal the publish", err)
+			}
+		}
 		response, err := daemon.StreamCreate(name, filename, amount, options)
 		if err != nil {
 			if strings.Contains(err.Error(), "failed: Multiple claims (") {
@@ -26,6 +186,13 @@ func publishAndRetryExistingNames(daemon *jsonrpc.Client, title, filename string
 			return nil, err
 		}
 		PublishedClaim := response.Outputs[0]
+		if journal != nil {
+			err = journal.Outcome(name, response.Txid, PublishedClaim.ClaimID)
+			if err != nil {
+				// the claim exists, the journal will be reconciled on the next run
+				log.Errorf("could not journal the claim %s of %s: %s", PublishedClaim.ClaimID, name, err.Error())
+			}
+		}
 		return &SyncSummary{ClaimID: PublishedClaim.ClaimID, ClaimName: name}, nil
 	}
 }
@@ -0,0 +1,32 @@
+package sources
+
+import "github.com/lbryio/lbry.go/v2/extras/errors"
+
+const (
+	ShortsPublish = "publish" // Shorts are published like any other video
+	ShortsSkip    = "skip"    // Shorts are not published
+	ShortsTag     = "tag"     // Shorts are published with the shorts tag
+	ShortsChannel = "channel" // Shorts are published under a separate LBRY channel
+)
+
+var ShortsPolicies = []string{ShortsPublish, ShortsSkip, ShortsTag, ShortsChannel}
+
+// ErrShortSkipped is returned when a video turns out to be a Short and the Shorts of the channel are skipped
+var ErrShortSkipped = errors.Base("the video is a short and shorts are not synced for this channel")
+
+const (
+	shortsTag = "shorts"
+	// maxShortDuration is the longest a Short can be, in seconds
+	maxShortDuration = 60
+)
+
+// mightBeShort returns true if a video of the given duration in seconds can be a Short. The dimensions of youtube
+// videos are only known once they're downloaded.
+func mightBeShort(duration float64) bool {
+	return duration > 0 && duration <= maxShortDuration
+}
+
+// isShort returns true for vertical videos that are no longer than a minute
+func isShort(duration float64, width uint, height uint) bool {
+	return mightBeShort(duration) && height > width
+}
@@ -0,0 +1,24 @@
+package sources
+
+import "testing"
+
+func TestIsShort(t *testing.T) {
+	cases := []struct {
+		duration float64
+		width    uint
+		height   uint
+		expected bool
+	}{
+		{45, 1080, 1920, true},
+		{60, 720, 1280, true},
+		{45, 1920, 1080, false},
+		{45, 1080, 1080, false},
+		{61, 1080, 1920, false},
+		{0, 1080, 1920, false},
+	}
+	for i, c := range cases {
+		if isShort(c.duration, c.width, c.height) != c.expected {
+			t.Errorf("case %d: expected %t for %+v", i, c.expected, c)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package sources
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/extras/jsonrpc"
+
+	"github.com/lbryio/ytsync/sdk"
+)
+
+const (
+	SourceYoutube = "youtube"
+	SourceLocal   = "local"
+	SourceFeed    = "feed"
+)
+
+var SourceTypes = []string{SourceYoutube, SourceLocal, SourceFeed}
+
+// Video is a single item of a Source that can be synced to LBRY
+type Video interface {
+	Size() *int64
+	ID() string
+	Title() string
+	IDAndNum() string
+	PlaylistPosition() int
+	PublishedAt() time.Time
+	// Metadata returns what's published along with the video. The thumbnail and the media details are only known once
+	// the video was synced
+	Metadata() Metadata
+	Sync(*jsonrpc.Client, SyncParams, *sdk.SyncedVideo, bool, *sync.RWMutex) (*SyncSummary, error)
+}
+
+// ChannelInfo holds the details of a channel on the source platform. They're used to create the LBRY channel
+type ChannelInfo struct {
+	Title        string
+	Description  string
+	ThumbnailURL string
+	BannerURL    string
+	Language     string
+	Country      string
+}
+
+// ListParams controls which videos are returned by Source.ListVideos
+type ListParams struct {
+	VideoDir     string
+	SyncedVideos map[string]sdk.SyncedVideo
+	VideosLimit  int
+	QuickSync    bool
+	// SeparateShorts numbers the videos that can be Shorts on their own so that they don't use up the VideosLimit
+	SeparateShorts bool
+}
+
+// Source is a platform from which videos can be mirrored to LBRY
+type Source interface {
+	// GetChannelInfo returns the details of the channel on the source platform
+	GetChannelInfo(channelID string) (*ChannelInfo, error)
+	// CountVideos returns the amount of videos the channel has on the source platform
+	CountVideos(channelID string) (uint64, error)
+	// ListVideos returns all the videos of the channel that should be considered for syncing
+	ListVideos(channelID string, params ListParams) ([]Video, error)
+}
+
+// Playlist is an ordered list of videos of a channel
+type Playlist struct {
+	ID           string
+	Title        string
+	Description  string
+	ThumbnailURL string
+	// VideoIDs are the IDs of the videos of the playlist, in playlist order
+	VideoIDs []string
+}
+
+// PlaylistSource is implemented by the sources that organize the videos of a channel in playlists
+type PlaylistSource interface {
+	// ListPlaylists returns the public playlists of the channel
+	ListPlaylists(channelID string) ([]Playlist, error)
+}
+
+// FingerprintedVideo is implemented by the videos whose metadata can be edited on the source platform after they're
+// published. The fingerprint is stored along with the synced video so that edits can be detected on later runs.
+type FingerprintedVideo interface {
+	// MetadataFingerprint summarizes the metadata that ends up in the claim, it's empty if the metadata is unknown
+	MetadataFingerprint() string
+}
+
+// RemovableVideo is implemented by the videos that can be deleted or hidden on the source platform after they're
+// published
+type RemovableVideo interface {
+	// RemovedAtSource returns true if the video is no longer available on the source platform
+	RemovedAtSource() bool
+}
+
+// LiveVideo is implemented by the videos that can be live broadcasts or premieres
+type LiveVideo interface {
+	// Upcoming returns true if the video is scheduled and can't be downloaded yet
+	Upcoming() bool
+	// Live returns true if the video is being broadcast
+	Live() bool
+}
+
+// PublishJournal records the claim of a video before it's created and once it is, so that a publish interrupted in
+// between can be told apart from one that never happened
+type PublishJournal interface {
+	// Intent is recorded right before the claim is created
+	Intent(claimName string) error
+	// Outcome is recorded as soon as the claim is created
+	Outcome(claimName string, txid string, claimID string) error
+}
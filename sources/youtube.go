@@ -0,0 +1,322 @@
+package sources
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	"github.com/lbryio/lbry.go/v2/extras/stop"
+
+	"github.com/lbryio/ytsync/ip_manager"
+	"github.com/lbryio/ytsync/thumbs"
+
+	"github.com/aws/aws-sdk-go/aws"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/api/googleapi/transport"
+	"google.golang.org/api/youtube/v3"
+)
+
+// YoutubeSource lists channel videos through the YouTube Data API
+type YoutubeSource struct {
+	apiKey    string
+	awsConfig aws.Config
+	stopGroup *stop.Group
+	pool      *ip_manager.IPPool
+}
+
+func NewYoutubeSource(apiKey string, awsConfig aws.Config, stopGroup *stop.Group, pool *ip_manager.IPPool) *YoutubeSource {
+	return &YoutubeSource{
+		apiKey:    apiKey,
+		awsConfig: awsConfig,
+		stopGroup: stopGroup,
+		pool:      pool,
+	}
+}
+
+func (y *YoutubeSource) getService() (*youtube.Service, error) {
+	client := &http.Client{
+		Transport: &transport.APIKey{Key: y.apiKey},
+	}
+
+	service, err := youtube.New(client)
+	if err != nil {
+		return nil, errors.Prefix("error creating YouTube service", err)
+	}
+	return service, nil
+}
+
+func (y *YoutubeSource) GetChannelInfo(channelID string) (*ChannelInfo, error) {
+	service, err := y.getService()
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := service.Channels.List("snippet,brandingSettings").Id(channelID).Do()
+	if err != nil {
+		return nil, errors.Prefix("error getting channel details", err)
+	}
+
+	if len(response.Items) < 1 {
+		return nil, errors.Err("youtube channel not found")
+	}
+
+	channelInfo := response.Items[0].Snippet
+	channelBranding := response.Items[0].BrandingSettings
+
+	info := &ChannelInfo{
+		Title:        channelInfo.Title,
+		Description:  channelInfo.Description,
+		ThumbnailURL: thumbs.GetBestThumbnail(channelInfo.Thumbnails).Url,
+		Language:     channelInfo.DefaultLanguage,
+		Country:      channelInfo.Country,
+	}
+	if info.Language == "iw" {
+		info.Language = "he"
+	}
+	if channelBranding.Image != nil {
+		info.BannerURL = channelBranding.Image.BannerImageUrl
+	}
+	return info, nil
+}
+
+func (y *YoutubeSource) CountVideos(channelID string) (uint64, error) {
+	service, err := y.getService()
+	if err != nil {
+		return 0, err
+	}
+
+	response, err := service.Channels.List("statistics").Id(channelID).Do()
+	if err != nil {
+		return 0, errors.Prefix("error getting channels", err)
+	}
+
+	if len(response.Items) < 1 {
+		return 0, errors.Err("youtube channel not found")
+	}
+
+	return response.Items[0].Statistics.VideoCount, nil
+}
+
+func (y *YoutubeSource) ListVideos(channelID string, params ListParams) ([]Video, error) {
+	service, err := y.getService()
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := service.Channels.List("contentDetails").Id(channelID).Do()
+	if err != nil {
+		return nil, errors.Prefix("error getting channels", err)
+	}
+
+	if len(response.Items) < 1 {
+		return nil, errors.Err("youtube channel not found")
+	}
+
+	if response.Items[0].ContentDetails.RelatedPlaylists == nil {
+		return nil, errors.Err("no related playlists")
+	}
+
+	playlistID := response.Items[0].ContentDetails.RelatedPlaylists.Uploads
+	if playlistID == "" {
+		return nil, errors.Err("no channel playlist")
+	}
+
+	var videos []Video
+	playlistMap := make(map[string]*youtube.PlaylistItemSnippet, 50)
+	shorts := int64(0)
+	counted := 0
+	nextPageToken := ""
+	for {
+		req := service.PlaylistItems.List("snippet").
+			PlaylistId(playlistID).
+			MaxResults(50).
+			PageToken(nextPageToken)
+
+		playlistResponse, err := req.Do()
+		if err != nil {
+			return nil, errors.Prefix("error getting playlist items", err)
+		}
+
+		if len(playlistResponse.Items) < 1 {
+			// If there are 50+ videos in a playlist but less than 50 are actually returned by the API, youtube will still redirect
+			// clients to a next page. Such next page will however be empty. This logic prevents ytsync from failing.
+			youtubeIsLying := len(videos) > 0
+			if youtubeIsLying {
+				break
+			}
+			return nil, errors.Err("playlist items not found")
+		}
+		videoIDs := make([]string, 50)
+		for i, item := range playlistResponse.Items {
+			// normally we'd send the video into the channel here, but youtube api doesn't have sorting
+			// so we have to get ALL the videos, then sort them, then send them in
+			playlistMap[item.Snippet.ResourceId.VideoId] = item.Snippet
+			videoIDs[i] = item.Snippet.ResourceId.VideoId
+		}
+		req2 := service.Videos.List("snippet,contentDetails,recordingDetails,liveStreamingDetails,status").Id(strings.Join(videoIDs[:], ","))
+
+		videosListResponse, err := req2.Do()
+		if err != nil {
+			return nil, errors.Prefix("error getting videos info", err)
+		}
+		videoMap := make(map[string]*youtube.Video, len(videosListResponse.Items))
+		for _, item := range videosListResponse.Items {
+			videoMap[item.Id] = item
+		}
+		for _, playlistItem := range playlistResponse.Items {
+			item, ok := videoMap[playlistItem.Snippet.ResourceId.VideoId]
+			if !ok {
+				continue
+			}
+			v := NewYoutubeVideo(params.VideoDir, item, playlistItem.Snippet.Position, y.awsConfig, y.stopGroup, y.pool)
+			if params.SeparateShorts && mightBeShort(v.expectedDuration()) {
+				v.playlistPosition = shorts
+				shorts++
+			} else {
+				v.playlistPosition -= shorts
+				counted++
+			}
+			videos = append(videos, v)
+		}
+
+		log.Infof("Got info for %d videos from youtube API", len(videos))
+
+		nextPageToken = playlistResponse.NextPageToken
+		if nextPageToken == "" || params.QuickSync || counted >= params.VideosLimit {
+			break
+		}
+	}
+	var mockedVideos []*YoutubeVideo
+	for k, v := range params.SyncedVideos {
+		if !v.Published {
+			continue
+		}
+		_, ok := playlistMap[k]
+		if !ok {
+			mockedVideos = append(mockedVideos, NewMockedVideo(params.VideoDir, k, channelID, y.awsConfig, y.stopGroup, y.pool))
+		}
+	}
+	// videos can be missing from the playlist only because the listing stopped early, so check whether they still exist
+	removed, err := y.findRemovedVideos(service, mockedVideos)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range mockedVideos {
+		v.removed = removed[v.ID()]
+		videos = append(videos, v)
+	}
+	return videos, nil
+}
+
+// findRemovedVideos returns the IDs of the given videos that were deleted or made private on youtube
+func (y *YoutubeSource) findRemovedVideos(service *youtube.Service, videos []*YoutubeVideo) (map[string]bool, error) {
+	removed := make(map[string]bool)
+	for start := 0; start < len(videos); start += 50 {
+		end := start + 50
+		if end > len(videos) {
+			end = len(videos)
+		}
+		ids := make([]string, 0, end-start)
+		for _, v := range videos[start:end] {
+			ids = append(ids, v.ID())
+			removed[v.ID()] = true
+		}
+		response, err := service.Videos.List("status").Id(strings.Join(ids, ",")).Do()
+		if err != nil {
+			return nil, errors.Prefix("error getting videos status", err)
+		}
+		for _, item := range response.Items {
+			removed[item.Id] = item.Status != nil && item.Status.PrivacyStatus == "private"
+		}
+	}
+	count := 0
+	for _, r := range removed {
+		if r {
+			count++
+		}
+	}
+	if count > 0 {
+		log.Infof("%d published videos were removed from youtube", count)
+	}
+	return removed, nil
+}
+
+func (y *YoutubeSource) ListPlaylists(channelID string) ([]Playlist, error) {
+	service, err := y.getService()
+	if err != nil {
+		return nil, err
+	}
+
+	var playlists []Playlist
+	nextPageToken := ""
+	for {
+		response, err := service.Playlists.List("snippet,status").
+			ChannelId(channelID).
+			MaxResults(50).
+			PageToken(nextPageToken).
+			Do()
+		if err != nil {
+			return nil, errors.Prefix("error getting playlists", err)
+		}
+		for _, item := range response.Items {
+			if item.Status == nil || item.Status.PrivacyStatus != "public" {
+				continue
+			}
+			videoIDs, err := y.listPlaylistVideos(service, item.Id)
+			if err != nil {
+				return nil, err
+			}
+			playlist := Playlist{
+				ID:          item.Id,
+				Title:       item.Snippet.Title,
+				Description: item.Snippet.Description,
+				VideoIDs:    videoIDs,
+			}
+			if item.Snippet.Thumbnails != nil {
+				playlist.ThumbnailURL = thumbs.GetBestThumbnail(item.Snippet.Thumbnails).Url
+			}
+			playlists = append(playlists, playlist)
+		}
+		nextPageToken = response.NextPageToken
+		if nextPageToken == "" {
+			break
+		}
+	}
+	log.Infof("Got %d public playlists from youtube API", len(playlists))
+	return playlists, nil
+}
+
+// listPlaylistVideos returns the IDs of the public videos of a playlist in playlist order
+func (y *YoutubeSource) listPlaylistVideos(service *youtube.Service, playlistID string) ([]string, error) {
+	var items []*youtube.PlaylistItem
+	nextPageToken := ""
+	for {
+		response, err := service.PlaylistItems.List("snippet,status").
+			PlaylistId(playlistID).
+			MaxResults(50).
+			PageToken(nextPageToken).
+			Do()
+		if err != nil {
+			return nil, errors.Prefix("error getting playlist items", err)
+		}
+		for _, item := range response.Items {
+			if item.Status != nil && item.Status.PrivacyStatus == "private" {
+				continue
+			}
+			items = append(items, item)
+		}
+		nextPageToken = response.NextPageToken
+		if nextPageToken == "" || len(response.Items) == 0 {
+			break
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Snippet.Position < items[j].Snippet.Position
+	})
+	videoIDs := make([]string, 0, len(items))
+	for _, item := range items {
+		videoIDs = append(videoIDs, item.Snippet.ResourceId.VideoId)
+	}
+	return videoIDs, nil
+}
@@ -1,16 +1,18 @@
 package sources
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"math"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
 	"sync"
+	"text/template"
 	"time"
 
 	"github.com/lbryio/lbry.go/v2/extras/errors"
@@ -18,7 +20,10 @@ import (
 	"github.com/lbryio/lbry.go/v2/extras/stop"
 	"github.com/lbryio/lbry.go/v2/extras/util"
 
+	"github.com/lbryio/ytsync/downloader"
+	"github.com/lbryio/ytsync/errclass"
 	"github.com/lbryio/ytsync/ip_manager"
+	"github.com/lbryio/ytsync/media"
 	"github.com/lbryio/ytsync/namer"
 	"github.com/lbryio/ytsync/sdk"
 	"github.com/lbryio/ytsync/tags_manager"
@@ -26,7 +31,6 @@ import (
 
 	duration "github.com/ChannelMeter/iso8601duration"
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/shopspring/decimal"
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/api/youtube/v3"
 )
@@ -51,8 +55,21 @@ type YoutubeVideo struct {
 	walletLock       *sync.RWMutex
 	stopGroup        *stop.Group
 	pool             *ip_manager.IPPool
+	downloader       downloader.Downloader
+	qualities        []string
+	subtitles        *downloader.SubtitleOptions
+	mediaInfo        *media.Info
+	fingerprint      string
+	removed          bool
+	short            bool
+	shortsPolicy     string
+	chapters         bool
+	// descriptionTemplate renders claim descriptions, the default template is used when it's nil
+	descriptionTemplate *template.Template
 }
 
+const progressLogInterval = 10 * time.Second
+
 var youtubeCategories = map[string]string{
 	"1":  "film & animation",
 	"2":  "autos & vehicles",
@@ -90,6 +107,13 @@ var youtubeCategories = map[string]string{
 
 func NewYoutubeVideo(directory string, videoData *youtube.Video, playlistPosition int64, awsConfig aws.Config, stopGroup *stop.Group, pool *ip_manager.IPPool) *YoutubeVideo {
 	publishedAt, _ := time.Parse(time.RFC3339Nano, videoData.Snippet.PublishedAt) // ignore parse errors
+	if details := videoData.LiveStreamingDetails; details != nil && details.ActualStartTime != "" {
+		//broadcasts are released when they start, not when they're scheduled
+		startedAt, err := time.Parse(time.RFC3339Nano, details.ActualStartTime)
+		if err == nil {
+			publishedAt = startedAt
+		}
+	}
 	return &YoutubeVideo{
 		id:               videoData.Id,
 		title:            videoData.Snippet.Title,
@@ -122,6 +146,10 @@ func (v *YoutubeVideo) ID() string {
 	return v.id
 }
 
+func (v *YoutubeVideo) Title() string {
+	return v.title
+}
+
 func (v *YoutubeVideo) PlaylistPosition() int {
 	return int(v.playlistPosition)
 }
@@ -164,18 +192,26 @@ func (v *YoutubeVideo) getFullPath() string {
 	return v.videoDir() + "/" + name + ".mp4"
 }
 
+func (v *YoutubeVideo) descriptionData() DescriptionData {
+	data := DescriptionData{
+		ID:              v.id,
+		Title:           v.title,
+		Description:     abbreviateDescription(v.description),
+		FullDescription: v.description,
+		PublishedAt:     v.publishedAt,
+		URL:             "https://www.youtube.com/watch?v=" + v.id,
+		Duration:        time.Duration(v.expectedDuration()) * time.Second,
+	}
+	data.Chapters, _, _ = splitChapters(v.description)
+	if v.youtubeInfo != nil {
+		data.ChannelName = v.youtubeInfo.Snippet.ChannelTitle
+	}
+	return data
+}
+
+// getAbbrevDescription renders the description template of the channel for the video
 func (v *YoutubeVideo) getAbbrevDescription() string {
-	maxLength := 2800
-	description := strings.TrimSpace(v.description)
-	additionalDescription := "\nhttps://www.youtube.com/watch?v=" + v.id
-	khanAcademyClaimID := "5fc52291980268b82413ca4c0ace1b8d749f3ffb"
-	if v.lbryChannelID == khanAcademyClaimID {
-		additionalDescription = additionalDescription + "\nNote: All Khan Academy content is available for free at (www.khanacademy.org)"
-	}
-	if len(description) > maxLength {
-		description = description[:maxLength]
-	}
-	return description + "\n..." + additionalDescription
+	return describe(v.descriptionTemplate, v.descriptionData())
 }
 
 func (v *YoutubeVideo) download() error {
@@ -193,39 +229,6 @@ func (v *YoutubeVideo) download() error {
 		log.Debugln(v.id + " already exists at " + videoPath)
 		return nil
 	}
-	qualities := []string{
-		"1080",
-		"720",
-		"480",
-		"320",
-	}
-	ytdlArgs := []string{
-		"--no-progress",
-		"-o" + strings.TrimSuffix(v.getFullPath(), ".mp4"),
-		"--merge-output-format",
-		"mp4",
-		"--postprocessor-args",
-		"-movflags faststart",
-		"--abort-on-unavailable-fragment",
-		"--fragment-retries",
-		"0",
-		"--user-agent",
-		"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/79.0.3945.88 Safari/537.36",
-		"--cookies",
-		"cookies.txt",
-	}
-	if v.maxVideoSize > 0 {
-		ytdlArgs = append(ytdlArgs,
-			"--max-filesize",
-			fmt.Sprintf("%dM", v.maxVideoSize),
-		)
-	}
-	if v.maxVideoLength > 0 {
-		ytdlArgs = append(ytdlArgs,
-			"--match-filter",
-			fmt.Sprintf("duration <= %d", int(math.Round(v.maxVideoLength*3600))),
-		)
-	}
 
 	var sourceAddress string
 	for {
@@ -234,7 +237,7 @@ func (v *YoutubeVideo) download() error {
 			if errors.Is(err, ip_manager.ErrAllThrottled) {
 				select {
 				case <-v.stopGroup.Ch():
-					return errors.Err("interrupted by user")
+					return errors.Err(downloader.ErrInterrupted)
 				default:
 					time.Sleep(ip_manager.IPCooldownPeriod)
 					continue
@@ -247,75 +250,68 @@ func (v *YoutubeVideo) download() error {
 	}
 	defer v.pool.ReleaseIP(sourceAddress)
 
-	ytdlArgs = append(ytdlArgs,
-		"--source-address",
-		sourceAddress,
-		"https://www.youtube.com/watch?v="+v.ID(),
-	)
-
-	for i, quality := range qualities {
-		argsWithFilters := append(ytdlArgs, "-fbestvideo[ext=mp4][height<="+quality+"]+bestaudio[ext!=webm]")
-		cmd := exec.Command("youtube-dl", argsWithFilters...)
-		log.Printf("Running command youtube-dl %s", strings.Join(argsWithFilters, " "))
-
-		stderr, err := cmd.StderrPipe()
-		if err != nil {
-			return errors.Err(err)
-		}
-		stdout, err := cmd.StdoutPipe()
-		if err != nil {
-			return errors.Err(err)
+	maxDuration := 0
+	if v.maxVideoLength > 0 {
+		maxDuration = int(math.Round(v.maxVideoLength * 3600))
+	}
+	err = v.downloader.Download(downloader.Options{
+		URL:           "https://www.youtube.com/watch?v=" + v.ID(),
+		OutputPath:    videoPath,
+		SourceAddress: sourceAddress,
+		MaxFileSize:   int(v.maxVideoSize),
+		MaxDuration:   maxDuration,
+		Qualities:     v.qualities,
+		Subtitles:     v.subtitleOptions(),
+		WriteInfo:     v.chapters,
+		OnProgress:    v.progressLogger(),
+		Stop:          v.stopGroup.Ch(),
+	})
+	if err != nil {
+		if errors.Is(err, downloader.ErrThrottled) {
+			v.pool.SetThrottled(sourceAddress)
 		}
+		return err
+	}
 
-		if err := cmd.Start(); err != nil {
-			return errors.Err(err)
-		}
+	fi, err := os.Stat(videoPath)
+	if err != nil {
+		return errors.Err(err)
+	}
+	err = os.Chmod(videoPath, 0777)
+	if err != nil {
+		return errors.Err(err)
+	}
+	videoSize := fi.Size()
+	v.size = &videoSize
+	return nil
+}
 
-		errorLog, _ := ioutil.ReadAll(stderr)
-		outLog, _ := ioutil.ReadAll(stdout)
-		err = cmd.Wait()
-		if err != nil {
-			if strings.Contains(err.Error(), "exit status 1") {
-				if strings.Contains(string(errorLog), "HTTP Error 429") || strings.Contains(string(errorLog), "returned non-zero exit status 8") {
-					v.pool.SetThrottled(sourceAddress)
-				} else if strings.Contains(string(errorLog), "giving up after 0 fragment retries") {
-					if i == (len(qualities) - 1) {
-						return errors.Err(string(errorLog))
-					}
-					continue //this bypasses the yt throttling IP redistribution... TODO: don't
-				}
-				return errors.Err(string(errorLog))
-			}
-			return errors.Err(err)
-		}
-		log.Debugln(string(outLog))
+// subtitleOptions restricts automatic captions to the language of the video when no language is configured, as
+// youtube offers automatic translations of them to every language
+func (v *YoutubeVideo) subtitleOptions() *downloader.SubtitleOptions {
+	if v.subtitles == nil || !v.subtitles.Auto || len(v.subtitles.Languages) > 0 {
+		return v.subtitles
+	}
+	language := "en"
+	if v.youtubeInfo != nil && v.youtubeInfo.Snippet.DefaultAudioLanguage != "" {
+		language = strings.Split(v.youtubeInfo.Snippet.DefaultAudioLanguage, "-")[0]
+	}
+	return &downloader.SubtitleOptions{
+		Languages: []string{language},
+		Auto:      true,
+	}
+}
 
-		if strings.Contains(string(outLog), "does not pass filter duration") {
-			_ = v.delete("does not pass filter duration")
-			return errors.Err("video is too long to process")
-		}
-		if strings.Contains(string(outLog), "File is larger than max-filesize") {
-			_ = v.delete("File is larger than max-filesize")
-			return errors.Err("the video is too big to sync, skipping for now")
-		}
-		if string(errorLog) != "" {
-			log.Printf("Command finished with error: %v", errors.Err(string(errorLog)))
-			_ = v.delete("due to error")
-			return errors.Err(string(errorLog))
-		}
-		fi, err := os.Stat(v.getFullPath())
-		if err != nil {
-			return errors.Err(err)
-		}
-		err = os.Chmod(v.getFullPath(), 0777)
-		if err != nil {
-			return errors.Err(err)
+// progressLogger returns a progress callback that logs at most once every progressLogInterval
+func (v *YoutubeVideo) progressLogger() func(downloader.Progress) {
+	var lastLog time.Time
+	return func(p downloader.Progress) {
+		if time.Since(lastLog) < progressLogInterval {
+			return
 		}
-		videoSize := fi.Size()
-		v.size = &videoSize
-		break
+		lastLog = time.Now()
+		log.Infof("downloading %s: %s", v.id, p.String())
 	}
-	return nil
 }
 
 func (v *YoutubeVideo) videoDir() string {
@@ -359,50 +355,156 @@ func (v *YoutubeVideo) delete(reason string) error {
 	return nil
 }
 
+// writeChapters embeds the chapters of the video in the downloaded file. The chapters found by the downloader are
+// preferred over the ones listed in the description.
+func (v *YoutubeVideo) writeChapters() error {
+	chapters, err := downloader.ReadChapters(v.getFullPath())
+	if err != nil {
+		return err
+	}
+	if len(chapters) == 0 {
+		chapters, _, _ = splitChapters(v.description)
+		if len(chapters) > 0 && v.mediaInfo != nil {
+			chapters[len(chapters)-1].End = v.mediaInfo.Duration
+		}
+	}
+	if len(chapters) == 0 || chapters[len(chapters)-1].End == 0 {
+		return nil
+	}
+	path, err := v.getDownloadedPath()
+	if err != nil {
+		return err
+	}
+	output := strings.TrimSuffix(v.getFullPath(), ".mp4") + ".chapters.mp4"
+	err = media.WriteChapters(path, output, chapters, v.stopGroup.Ch())
+	if err != nil {
+		return err
+	}
+	err = os.Rename(output, path)
+	if err != nil {
+		return errors.Err(err)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return errors.Err(err)
+	}
+	size := fi.Size()
+	v.size = &size
+	log.Debugf("wrote %d chapters in %s", len(chapters), path)
+	return nil
+}
+
 func (v *YoutubeVideo) triggerThumbnailSave() (err error) {
 	thumbnail := thumbs.GetBestThumbnail(v.youtubeInfo.Snippet.Thumbnails)
 	v.thumbnailURL, err = thumbs.MirrorThumbnail(thumbnail.Url, v.ID(), v.awsConfig)
 	return err
 }
 
-func (v *YoutubeVideo) publish(daemon *jsonrpc.Client, params SyncParams) (*SyncSummary, error) {
+func (v *YoutubeVideo) Metadata() Metadata {
 	languages, locations, tags := v.getMetadata()
-	var fee *jsonrpc.Fee
-	if params.Fee != nil {
-		feeAmount, err := decimal.NewFromString(params.Fee.Amount)
-		if err != nil {
-			return nil, errors.Err(err)
-		}
-		fee = &jsonrpc.Fee{
-			FeeAddress:  &params.Fee.Address,
-			FeeAmount:   feeAmount,
-			FeeCurrency: jsonrpc.Currency(params.Fee.Currency),
-		}
+	if v.short && v.shortsPolicy == ShortsTag {
+		tags = append(tags, shortsTag)
+	}
+	return Metadata{
+		Title:        v.title,
+		Description:  v.getAbbrevDescription(),
+		Tags:         tags,
+		Languages:    languages,
+		Locations:    locations,
+		ReleaseTime:  v.publishedAt,
+		ThumbnailURL: v.thumbnailURL,
+		Media:        v.mediaInfo,
+		License:      v.license(),
 	}
+}
 
-	options := jsonrpc.StreamCreateOptions{
-		ClaimCreateOptions: jsonrpc.ClaimCreateOptions{
-			Title:        &v.title,
-			Description:  util.PtrToString(v.getAbbrevDescription()),
-			ClaimAddress: &params.ClaimAddress,
-			Languages:    languages,
-			ThumbnailURL: &v.thumbnailURL,
-			Tags:         tags,
-			Locations:    locations,
-			FundingAccountIDs: []string{
-				params.DefaultAccount,
-			},
-		},
-		Fee:         fee,
-		License:     util.PtrToString("Copyrighted (contact publisher)"),
-		ReleaseTime: util.PtrToInt64(v.publishedAt.Unix()),
-		ChannelID:   &v.lbryChannelID,
+// license maps the license of the video on youtube, it's empty for mocked videos
+func (v *YoutubeVideo) license() License {
+	if v.youtubeInfo == nil || v.youtubeInfo.Status == nil {
+		return License{}
+	}
+	if v.youtubeInfo.Status.License == "creativeCommon" {
+		return youtubeCreativeCommons
+	}
+	return DefaultLicense
+}
+
+func (v *YoutubeVideo) Upcoming() bool {
+	return v.youtubeInfo != nil && v.youtubeInfo.Snippet.LiveBroadcastContent == "upcoming"
+}
+
+func (v *YoutubeVideo) Live() bool {
+	if v.youtubeInfo == nil {
+		return false
+	}
+	if v.youtubeInfo.Snippet.LiveBroadcastContent == "live" {
+		return true
+	}
+	details := v.youtubeInfo.LiveStreamingDetails
+	return details != nil && details.ActualStartTime != "" && details.ActualEndTime == ""
+}
+
+// isVOD returns true if the video is the recording of a finished broadcast or premiere
+func (v *YoutubeVideo) isVOD() bool {
+	return v.youtubeInfo != nil && v.youtubeInfo.LiveStreamingDetails != nil && v.youtubeInfo.LiveStreamingDetails.ActualEndTime != ""
+}
+
+func (v *YoutubeVideo) RemovedAtSource() bool {
+	return v.removed
+}
+
+// MetadataFingerprint hashes the metadata that is published from the youtube snippet of the video, along with the
+// version of its thumbnail. The fingerprint is unknown, empty, if the version of the thumbnail can't be looked up
+func (v *YoutubeVideo) MetadataFingerprint() string {
+	if v.mocked || v.youtubeInfo == nil {
+		return ""
+	}
+	if v.fingerprint != "" {
+		return v.fingerprint
+	}
+	languages, _, tags := v.getMetadata()
+	thumbnail := thumbs.GetBestThumbnail(v.youtubeInfo.Snippet.Thumbnails)
+	thumbnailVersion, err := thumbs.GetThumbnailVersion(thumbnail.Url)
+	if err != nil {
+		log.Warnf("%s: could not look up the version of the thumbnail: %s", v.id, err.Error())
+		return ""
+	}
+	//the template of the channel isn't part of the metadata of the video
+	defaultDescription, _ := renderDescription(nil, v.descriptionData())
+	h := sha256.New()
+	for _, field := range []string{
+		v.title,
+		defaultDescription,
+		strings.Join(tags, ","),
+		strings.Join(languages, ","),
+		thumbnail.Url,
+		thumbnailVersion,
+	} {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	v.fingerprint = hex.EncodeToString(h.Sum(nil))
+	return v.fingerprint
+}
+
+// expectedDuration returns the duration announced by youtube in seconds, or 0 if it's unknown
+func (v *YoutubeVideo) expectedDuration() float64 {
+	if v.youtubeInfo == nil || v.youtubeInfo.ContentDetails == nil {
+		return 0
+	}
+	videoDuration, err := duration.FromString(v.youtubeInfo.ContentDetails.Duration)
+	if err != nil {
+		return 0
 	}
+	return videoDuration.ToDuration().Seconds()
+}
+
+func (v *YoutubeVideo) publish(daemon *jsonrpc.Client, params SyncParams) (*SyncSummary, error) {
 	downloadPath, err := v.getDownloadedPath()
 	if err != nil {
 		return nil, err
 	}
-	return publishAndRetryExistingNames(daemon, v.title, downloadPath, params.Amount, options, params.Namer, v.walletLock)
+	return publishVideo(daemon, v.Metadata(), downloadPath, params, v.walletLock)
 }
 
 func (v *YoutubeVideo) Size() *int64 {
@@ -418,11 +520,43 @@ type SyncParams struct {
 	MaxVideoLength float64
 	Fee            *sdk.Fee
 	DefaultAccount string
+	Downloader     downloader.Downloader
+	// Qualities is the format ladder used by the downloader, see downloader.DefaultQualities
+	Qualities []string
+	// Subtitles enables the download of captions, nil disables it
+	Subtitles *downloader.SubtitleOptions
+	// MetadataChanged is set when the metadata of a published video changed on the source and its claim must be
+	// updated, thumbnail included
+	MetadataChanged bool
+	// TranscodeProfile is the profile files are transcoded to before being published, nil disables transcoding
+	TranscodeProfile *media.Profile
+	// MaxVODLength is the maximum length in hours of the recordings of finished broadcasts, 0 disables the check
+	MaxVODLength float64
+	// ShortsPolicy is one of ShortsPolicies and controls how Shorts are published
+	ShortsPolicy string
+	// ShortsChannelID is the LBRY channel Shorts are published under with the ShortsChannel policy
+	ShortsChannelID string
+	// Chapters enables writing the chapters of videos in the published files
+	Chapters bool
+	// DescriptionTemplate renders the descriptions of claims, see ParseDescriptionTemplate. nil means the default
+	DescriptionTemplate *template.Template
+	// License overrides the license of the videos on the source when set
+	License *License
+	// Tags are added to the tags of every claim
+	Tags []string
+	// Journal records the claim of the video before and after it's created, nil disables it
+	Journal PublishJournal
 }
 
 func (v *YoutubeVideo) Sync(daemon *jsonrpc.Client, params SyncParams, existingVideoData *sdk.SyncedVideo, reprocess bool, walletLock *sync.RWMutex) (*SyncSummary, error) {
 	v.maxVideoSize = int64(params.MaxVideoSize)
 	v.maxVideoLength = params.MaxVideoLength
+	v.downloader = params.Downloader
+	v.qualities = params.Qualities
+	v.subtitles = params.Subtitles
+	v.shortsPolicy = params.ShortsPolicy
+	v.chapters = params.Chapters
+	v.descriptionTemplate = params.DescriptionTemplate
 	v.lbryChannelID = params.ChannelID
 	v.walletLock = walletLock
 	if reprocess && existingVideoData != nil && existingVideoData.Published {
@@ -433,10 +567,15 @@ func (v *YoutubeVideo) Sync(daemon *jsonrpc.Client, params SyncParams, existingV
 }
 
 func (v *YoutubeVideo) downloadAndPublish(daemon *jsonrpc.Client, params SyncParams) (*SyncSummary, error) {
+	if params.MaxVODLength > 0 && v.isVOD() && v.expectedDuration() > params.MaxVODLength*3600 {
+		return nil, errors.Prefix("livestream", downloader.ErrTooLong)
+	}
+	// only the videos short enough, or of unknown duration, need their dimensions checked once downloaded
+	maybeShort := v.expectedDuration() == 0 || mightBeShort(v.expectedDuration())
 	var err error
 	for {
 		err = v.download()
-		if err != nil && strings.Contains(err.Error(), "HTTP Error 429") {
+		if err != nil && errors.Is(err, downloader.ErrThrottled) {
 			continue
 		} else if err != nil {
 			return nil, errors.Prefix("download error", err)
@@ -446,6 +585,62 @@ func (v *YoutubeVideo) downloadAndPublish(daemon *jsonrpc.Client, params SyncPar
 
 	log.Debugln("Downloaded " + v.id)
 
+	downloadPath, err := v.getDownloadedPath()
+	if err != nil {
+		return nil, errors.Prefix("download error", err)
+	}
+	v.mediaInfo, err = probeMedia(downloadPath, media.Expectations{
+		Duration:     v.expectedDuration(),
+		RequireVideo: true,
+		RequireAudio: true,
+	})
+	if err != nil {
+		//the file is unusable, make sure it's downloaded again on the next attempt
+		_ = v.delete("probe failed")
+		return nil, errors.Prefix("probe error", err)
+	}
+	if maybeShort {
+		shortDuration := v.expectedDuration()
+		if shortDuration == 0 {
+			shortDuration = v.mediaInfo.Duration
+		}
+		v.short = isShort(shortDuration, v.mediaInfo.Width, v.mediaInfo.Height)
+	}
+	if v.short {
+		switch params.ShortsPolicy {
+		case ShortsSkip:
+			_ = v.delete("shorts are skipped")
+			return nil, errors.Err(ErrShortSkipped)
+		case ShortsChannel:
+			params.ChannelID = params.ShortsChannelID
+		}
+	}
+
+	transcoded, err := transcodeIfNeeded(downloadPath, strings.TrimSuffix(v.getFullPath(), ".mp4")+".transcoding.mp4", v.mediaInfo, params, v.stopGroup.Ch())
+	if err != nil {
+		return nil, errors.Prefix("transcode error", err)
+	}
+	if transcoded != nil {
+		//replace the download with the transcoded file so that it's the one published and deleted
+		err = os.Remove(downloadPath)
+		if err != nil {
+			return nil, errors.Prefix("transcode error", err)
+		}
+		err = os.Rename(transcoded.path, v.getFullPath())
+		if err != nil {
+			return nil, errors.Prefix("transcode error", err)
+		}
+		v.mediaInfo = transcoded.info
+		v.size = &transcoded.size
+	}
+
+	if params.Chapters {
+		err = v.writeChapters()
+		if err != nil {
+			return nil, errors.Prefix("chapters error", err)
+		}
+	}
+
 	err = v.triggerThumbnailSave()
 	if err != nil {
 		return nil, errors.Prefix("thumbnail error", err)
@@ -503,16 +698,29 @@ func (v *YoutubeVideo) reprocess(daemon *jsonrpc.Client, params SyncParams, exis
 
 	currentClaim := c.Claims[0]
 	languages, locations, tags := v.getMetadata()
+	tags = appendTags(tags, params.Tags)
+	channelID := v.lbryChannelID
+	if video := currentClaim.Value.GetStream().GetVideo(); video != nil {
+		v.short = isShort(float64(video.GetDuration()), uint(video.GetWidth()), uint(video.GetHeight()))
+	}
+	if v.short && params.ShortsPolicy == ShortsTag {
+		tags = append(tags, shortsTag)
+	} else if v.short && params.ShortsPolicy == ShortsChannel {
+		channelID = params.ShortsChannelID
+	}
 
 	thumbnailURL := ""
-	if currentClaim.Value.GetThumbnail() == nil {
+	if currentClaim.Value.GetThumbnail() == nil || params.MetadataChanged {
 		if v.mocked {
 			return nil, errors.Err("could not find thumbnail for mocked video")
 		}
 		thumbnail := thumbs.GetBestThumbnail(v.youtubeInfo.Snippet.Thumbnails)
 		thumbnailURL, err = thumbs.MirrorThumbnail(thumbnail.Url, v.ID(), v.awsConfig)
+		if err != nil {
+			return nil, errors.Prefix("thumbnail error", err)
+		}
 	} else {
-		thumbnailURL = thumbs.ThumbnailEndpoint + v.ID()
+		thumbnailURL = thumbs.ThumbnailEndpoint + thumbs.ThumbnailName(v.ID())
 	}
 
 	videoSize, err := currentClaim.GetStreamSizeByMagic()
@@ -529,22 +737,21 @@ func (v *YoutubeVideo) reprocess(daemon *jsonrpc.Client, params SyncParams, exis
 				return v.downloadAndPublish(daemon, params)
 
 			}
-			return nil, errors.Err("the video must be republished as we can't get the right size but it doesn't exist on youtube anymore")
+			return nil, errclass.Wrap(errclass.NoRetry, errors.Base("the video must be republished as we can't get the right size but it doesn't exist on youtube anymore"))
 		}
 	}
 	v.size = util.PtrToInt64(int64(videoSize))
-	var fee *jsonrpc.Fee
-	if params.Fee != nil {
-		feeAmount, err := decimal.NewFromString(params.Fee.Amount)
-		if err != nil {
-			return nil, errors.Err(err)
-		}
-		fee = &jsonrpc.Fee{
-			FeeAddress:  &params.Fee.Address,
-			FeeAmount:   feeAmount,
-			FeeCurrency: jsonrpc.Currency(params.Fee.Currency),
-		}
+	fee, err := getFee(params)
+	if err != nil {
+		return nil, err
+	}
+	license := v.license()
+	if license.Name == "" {
+		//mocked videos keep the license they were published with
+		license.Name = currentClaim.Value.GetStream().GetLicense()
+		license.URL = currentClaim.Value.GetStream().GetLicenseUrl()
 	}
+	license = claimLicense(license, params)
 	streamCreateOptions := &jsonrpc.StreamCreateOptions{
 		ClaimCreateOptions: jsonrpc.ClaimCreateOptions{
 			Tags:         tags,
@@ -555,12 +762,16 @@ func (v *YoutubeVideo) reprocess(daemon *jsonrpc.Client, params SyncParams, exis
 				params.DefaultAccount,
 			},
 		},
-		Author:    util.PtrToString(""),
-		License:   util.PtrToString("Copyrighted (contact publisher)"),
-		ChannelID: &v.lbryChannelID,
-		Height:    util.PtrToUint(720),
-		Width:     util.PtrToUint(1280),
-		Fee:       fee,
+		Author:     util.PtrToString(""),
+		License:    &license.Name,
+		LicenseURL: &license.URL,
+		ChannelID:  &channelID,
+		Fee:        fee,
+	}
+	// the file isn't downloaded again when reprocessing, so the dimensions probed at publish time are kept
+	if video := currentClaim.Value.GetStream().GetVideo(); video != nil && video.GetHeight() > 0 {
+		streamCreateOptions.Height = util.PtrToUint(uint(video.GetHeight()))
+		streamCreateOptions.Width = util.PtrToUint(uint(video.GetWidth()))
 	}
 
 	v.walletLock.RLock()
@@ -0,0 +1,68 @@
+package sources
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+)
+
+// ytdlInfo is the subset of the youtube-dl .info.json format used by ytsync
+type ytdlInfo struct {
+	Type        string   `json:"_type"`
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+	Categories  []string `json:"categories"`
+	UploadDate  string   `json:"upload_date"`
+	Timestamp   float64  `json:"timestamp"`
+	Thumbnail   string   `json:"thumbnail"`
+	Thumbnails  []struct {
+		URL string `json:"url"`
+	} `json:"thumbnails"`
+	WebpageURL    string  `json:"webpage_url"`
+	Channel       string  `json:"channel"`
+	ChannelID     string  `json:"channel_id"`
+	Uploader      string  `json:"uploader"`
+	Duration      float64 `json:"duration"`
+	Width         int     `json:"width"`
+	Height        int     `json:"height"`
+	Language      string  `json:"language"`
+	License       string  `json:"license"`
+	PlaylistIndex int     `json:"playlist_index"`
+}
+
+func loadYtdlInfo(path string) (*ytdlInfo, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	var info ytdlInfo
+	err = json.Unmarshal(raw, &info)
+	if err != nil {
+		return nil, errors.Prefix("invalid info file "+path, err)
+	}
+	return &info, nil
+}
+
+// releaseTime returns the most precise publishing time available in the info file
+func (i *ytdlInfo) releaseTime() time.Time {
+	if i.Timestamp > 0 {
+		return time.Unix(int64(i.Timestamp), 0)
+	}
+	uploadDate, err := time.Parse("20060102", i.UploadDate)
+	if err != nil {
+		return time.Unix(0, 0)
+	}
+	return uploadDate
+}
+
+// bestThumbnail returns the thumbnail url with the highest resolution (youtube-dl sorts them in ascending order)
+func (i *ytdlInfo) bestThumbnail() string {
+	if len(i.Thumbnails) > 0 {
+		return i.Thumbnails[len(i.Thumbnails)-1].URL
+	}
+	return i.Thumbnail
+}
@@ -1,9 +1,14 @@
 package thumbs
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"io"
 	"net/http"
 	"os"
+	"regexp"
+	"strings"
 
 	"github.com/lbryio/lbry.go/v2/extras/errors"
 
@@ -22,7 +27,50 @@ type thumbnailUploader struct {
 }
 
 const thumbnailPath = "/tmp/ytsync_thumbnails/"
-const ThumbnailEndpoint = "https://thumbnails.lbry.com/"
+
+// DefaultBucket and DefaultEndpoint are where the thumbnails of LBRY's own syncs are mirrored to
+const DefaultBucket = "thumbnails.lbry.com"
+const DefaultEndpoint = "https://thumbnails.lbry.com/"
+
+// ThumbnailEndpoint is the public URL the mirrored thumbnails are served from, they're named after the videos
+var ThumbnailEndpoint = DefaultEndpoint
+var thumbnailBucket = DefaultBucket
+
+// SetStore sets the bucket the thumbnails are mirrored to and the public URL the bucket is served from
+func SetStore(bucket string, publicURL string) {
+	thumbnailBucket = bucket
+	if !strings.HasSuffix(publicURL, "/") {
+		publicURL += "/"
+	}
+	ThumbnailEndpoint = publicURL
+}
+
+var safeName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// escapedPrefix starts the names of the thumbnails whose video ID had to be escaped, it's never part of a safe name
+const escapedPrefix = "~"
+
+// ThumbnailName returns the name the thumbnail of a video is mirrored under. IDs that can't be part of a URL path, like
+// the GUIDs of feed items, are base64 encoded so VideoIDFromURL can read them back
+func ThumbnailName(videoID string) string {
+	if safeName.MatchString(videoID) {
+		return videoID
+	}
+	return escapedPrefix + base64.RawURLEncoding.EncodeToString([]byte(videoID))
+}
+
+// VideoIDFromURL returns the ID of the video a mirrored thumbnail belongs to
+func VideoIDFromURL(thumbnailURL string) string {
+	name := thumbnailURL[strings.LastIndex(thumbnailURL, "/")+1:]
+	if !strings.HasPrefix(name, escapedPrefix) {
+		return name
+	}
+	videoID, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(name, escapedPrefix))
+	if err != nil {
+		return name
+	}
+	return string(videoID)
+}
 
 func (u *thumbnailUploader) downloadThumbnail() error {
 	_ = os.Mkdir(thumbnailPath, 0777)
@@ -32,13 +80,23 @@ func (u *thumbnailUploader) downloadThumbnail() error {
 	}
 	defer img.Close()
 
-	resp, err := http.Get(u.originalUrl)
-	if err != nil {
-		return errors.Err(err)
+	var body io.ReadCloser
+	if strings.HasPrefix(u.originalUrl, "http://") || strings.HasPrefix(u.originalUrl, "https://") {
+		resp, err := http.Get(u.originalUrl)
+		if err != nil {
+			return errors.Err(err)
+		}
+		body = resp.Body
+	} else {
+		// thumbnails of local sources are already on disk
+		body, err = os.Open(strings.TrimPrefix(u.originalUrl, "file://"))
+		if err != nil {
+			return errors.Err(err)
+		}
 	}
-	defer resp.Body.Close()
+	defer body.Close()
 
-	_, err = io.Copy(img, resp.Body)
+	_, err = io.Copy(img, body)
 	if err != nil {
 		return errors.Err(err)
 	}
@@ -61,7 +119,7 @@ func (u *thumbnailUploader) uploadThumbnail() error {
 	uploader := s3manager.NewUploader(s3Session)
 
 	_, err = uploader.Upload(&s3manager.UploadInput{
-		Bucket:       aws.String("thumbnails.lbry.com"),
+		Bucket:       aws.String(thumbnailBucket),
 		Key:          key,
 		Body:         thumb,
 		ACL:          aws.String("public-read"),
@@ -78,10 +136,13 @@ func (u *thumbnailUploader) deleteTmpFile() {
 		log.Infof("failed to delete local thumbnail file: %s", err.Error())
 	}
 }
+
+// MirrorThumbnail uploads the image at url under the name of the video (or the channel) it belongs to and returns its
+// public URL
 func MirrorThumbnail(url string, name string, s3Config aws.Config) (string, error) {
 	tu := thumbnailUploader{
 		originalUrl: url,
-		name:        name,
+		name:        ThumbnailName(name),
 		s3Config:    s3Config,
 	}
 	err := tu.downloadThumbnail()
@@ -110,3 +171,37 @@ func GetBestThumbnail(thumbnails *youtube.ThumbnailDetails) *youtube.Thumbnail {
 	}
 	return thumbnails.Default
 }
+
+// GetThumbnailVersion identifies the current version of a remote thumbnail using the ETag or the Last-Modified header,
+// or a hash of the image when the server provides neither. Youtube keeps the same URL when a thumbnail is replaced, so
+// the URL alone doesn't tell the change.
+func GetThumbnailVersion(url string) (string, error) {
+	resp, err := http.Head(url)
+	if err != nil {
+		return "", errors.Err(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Err("non 200 status code received: %d", resp.StatusCode)
+	}
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		return etag, nil
+	}
+	if lastModified := resp.Header.Get("Last-Modified"); lastModified != "" {
+		return lastModified, nil
+	}
+	resp, err = http.Get(url)
+	if err != nil {
+		return "", errors.Err(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Err("non 200 status code received: %d", resp.StatusCode)
+	}
+	h := sha256.New()
+	_, err = io.Copy(h, resp.Body)
+	if err != nil {
+		return "", errors.Err(err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,18 @@
+package thumbs
+
+import "testing"
+
+func TestThumbnailName(t *testing.T) {
+	for _, id := range []string{"dQw4w9WgXcQ", "banner-UCaaa", "https://example.com/?p=1", "~abc", "tag:example.com,2019:ep/1"} {
+		name := ThumbnailName(id)
+		if !safeName.MatchString(id) && !safeName.MatchString(name[len(escapedPrefix):]) {
+			t.Errorf("%s: %q can't be part of a URL path", id, name)
+		}
+		if got := VideoIDFromURL(DefaultEndpoint + name); got != id {
+			t.Errorf("%s: read back %q from %s", id, got, name)
+		}
+	}
+	if name := ThumbnailName("dQw4w9WgXcQ"); name != "dQw4w9WgXcQ" {
+		t.Errorf("safe IDs must be kept as they are, got %s", name)
+	}
+}
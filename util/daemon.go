@@ -0,0 +1,282 @@
+package util
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/mitchellh/go-ps"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultInstanceName is the name of the systemd service and of the docker container of the default daemon
+const DefaultInstanceName = "lbrynet"
+
+// Instance is a lbrynet daemon along with the directories it keeps its data in.
+// Syncs running at the same time each need their own instance
+type Instance struct {
+	// Name is the systemd service (without the .service suffix) or the docker container running the daemon.
+	// In the process mode it only identifies the daemon
+	Name string `yaml:"name"`
+	// Address is the address of the API of the daemon. It defaults to the default address of lbrynet
+	Address string `yaml:"address"`
+	// LbrynetDir, BlobsDir and WalletsDir default to the directories of the environment
+	LbrynetDir string `yaml:"lbrynet_dir"`
+	BlobsDir   string `yaml:"blobs_dir"`
+	WalletsDir string `yaml:"wallets_dir"`
+	// TCPPort and UDPPort are the peer ports of the daemon in the process mode, the ones of the lbrynet config by default
+	TCPPort int `yaml:"tcp_port"`
+	UDPPort int `yaml:"udp_port"`
+}
+
+func (i Instance) GetBlobsDir() string {
+	if i.BlobsDir != "" {
+		return i.BlobsDir
+	}
+	if GetDaemonMode() == DaemonModeProcess && i.LbrynetDir != "" {
+		// the daemons run by ytsync keep their blobs in their data directory
+		return filepath.Join(i.LbrynetDir, "blobfiles")
+	}
+	return GetBlobsDir()
+}
+
+func (i Instance) GetLBRYNetDir() string {
+	if i.LbrynetDir != "" {
+		return i.LbrynetDir
+	}
+	return GetLBRYNetDir()
+}
+
+// GetWalletsDir returns the directory holding the wallets directory of the daemon
+func (i Instance) GetWalletsDir() string {
+	if i.WalletsDir != "" {
+		return i.WalletsDir
+	}
+	if environment.WalletsDir != "" {
+		return environment.WalletsDir
+	}
+	if IsRegTest() {
+		return os.Getenv("HOME") + "/.lbryum_regtest"
+	}
+	return os.Getenv("HOME") + "/.lbryum"
+}
+
+func (i Instance) GetDefaultWalletPath() string {
+	return i.GetWalletsDir() + "/wallets/default_wallet"
+}
+
+func (i Instance) name() string {
+	if i.Name == "" {
+		return DefaultInstanceName
+	}
+	return i.Name
+}
+
+func (i Instance) String() string {
+	return i.name()
+}
+
+func (i Instance) IsRunning() (bool, error) {
+	switch GetDaemonMode() {
+	case DaemonModeProcess:
+		return getSupervisor(i).isRunning(), nil
+	case DaemonModeDocker:
+		container, err := getDockerContainer(i.name(), ONLINE)
+		if err != nil {
+			return false, err
+		}
+		return container != nil, nil
+	}
+
+	pid, err := i.mainPID()
+	if err != nil {
+		return true, err
+	}
+	if pid == 0 {
+		return false, nil
+	}
+	process, err := ps.FindProcess(pid)
+	if err != nil {
+		return true, errors.Err(err)
+	}
+	return process != nil, nil
+}
+
+// HasFailed returns true if the daemon, run by ytsync, crashed and couldn't be restarted
+func (i Instance) HasFailed() bool {
+	return GetDaemonMode() == DaemonModeProcess && getSupervisor(i).hasFailed()
+}
+
+// mainPID returns the process of the systemd service of the daemon, 0 if it isn't running
+func (i Instance) mainPID() (int, error) {
+	out, err := exec.Command("systemctl", "show", "--property", "MainPID", "--value", i.name()+".service").Output()
+	if err != nil {
+		return 0, errors.Err(err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	if err != nil {
+		return 0, errors.Err(err)
+	}
+	return pid, nil
+}
+
+func (i Instance) Start() error {
+	switch GetDaemonMode() {
+	case DaemonModeProcess:
+		return getSupervisor(i).start()
+	case DaemonModeDocker:
+		return i.startViaDocker()
+	}
+	return i.systemctl("start")
+}
+
+// Stop stops the daemon. In the process mode it returns once the process exited
+func (i Instance) Stop() error {
+	switch GetDaemonMode() {
+	case DaemonModeProcess:
+		return getSupervisor(i).stop()
+	case DaemonModeDocker:
+		return i.stopViaDocker()
+	}
+	return i.systemctl("stop")
+}
+
+func (i Instance) startViaDocker() error {
+	container, err := getDockerContainer(i.name(), ALL)
+	if err != nil {
+		return err
+	}
+	if container == nil {
+		return errors.Err("no %s container found", i.name())
+	}
+
+	cli, err := client.NewEnvClient()
+	if err != nil {
+		panic(err)
+	}
+
+	err = cli.ContainerStart(context.Background(), container.ID, types.ContainerStartOptions{})
+	if err != nil {
+		return errors.Err(err)
+	}
+
+	return nil
+}
+
+func (i Instance) stopViaDocker() error {
+	container, err := getDockerContainer(i.name(), ONLINE)
+	if err != nil {
+		return err
+	}
+	if container == nil {
+		return nil
+	}
+
+	cli, err := client.NewEnvClient()
+	if err != nil {
+		panic(err)
+	}
+
+	err = cli.ContainerStop(context.Background(), container.ID, nil)
+	if err != nil {
+		return errors.Err(err)
+	}
+
+	return nil
+}
+
+func (i Instance) systemctl(command string) error {
+	err := exec.Command("/usr/bin/sudo", "/bin/systemctl", command, i.name()+".service").Run()
+	if err != nil {
+		return errors.Err(err)
+	}
+	return nil
+}
+
+// CleanForStartup wipes the wallet, the database and the blobs of the daemon. It's only allowed in regtest
+func (i Instance) CleanForStartup(lbrycrdString string) error {
+	if !IsRegTest() {
+		return errors.Err("never cleanup wallet outside of regtest and with caution. this should only be done in local testing and requires regtest to be on")
+	}
+
+	running, err := i.IsRunning()
+	if err != nil {
+		return err
+	}
+	if running {
+		err := i.Stop()
+		if err != nil {
+			return err
+		}
+	}
+
+	err = i.Cleanup()
+	if err != nil {
+		return errors.Err(err)
+	}
+
+	lbrycrd, err := GetLbrycrdClient(lbrycrdString)
+	if err != nil {
+		return errors.Prefix("error getting lbrycrd client: ", err)
+	}
+	height, err := lbrycrd.GetBlockCount()
+	if err != nil {
+		return errors.Err(err)
+	}
+	const minBlocksForUTXO = 200
+	if height < minBlocksForUTXO {
+		//Start reg test with some credits
+		txs, err := lbrycrd.Generate(uint32(minBlocksForUTXO) - uint32(height))
+		if err != nil {
+			return errors.Err(err)
+		}
+		log.Debugf("REGTEST: Generated %d transactions to get some LBC!", len(txs))
+	}
+
+	defaultWalletDir := i.GetDefaultWalletPath()
+	_, err = os.Stat(defaultWalletDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return errors.Err(os.Remove(defaultWalletDir))
+}
+
+// Cleanup removes the database and the blobs of the daemon, which must be stopped
+func (i Instance) Cleanup() error {
+	//make sure lbrynet is off
+	running, err := i.IsRunning()
+	if err != nil {
+		return err
+	}
+	if running {
+		return errors.Prefix("cannot cleanup lbrynet as the daemon is running", err)
+	}
+	lbrynetDir := i.GetLBRYNetDir()
+	files, err := filepath.Glob(filepath.Join(lbrynetDir, "lbrynet.sqlite*"))
+	if err != nil {
+		return errors.Err(err)
+	}
+	for _, f := range files {
+		err = os.Remove(f)
+		if err != nil {
+			return errors.Err(err)
+		}
+	}
+	blobsDir := i.GetBlobsDir()
+	err = os.RemoveAll(blobsDir)
+	if err != nil {
+		return errors.Err(err)
+	}
+	err = os.Mkdir(blobsDir, 0777)
+	if err != nil {
+		return errors.Err(err)
+	}
+	return nil
+}
@@ -0,0 +1,306 @@
+package util
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// maxDaemonRestarts is how many times a crashed daemon is restarted before giving up on it
+	maxDaemonRestarts = 5
+	// daemonStopTimeout is how long the daemon has to exit once asked to, after which it's killed
+	daemonStopTimeout = 3 * time.Minute
+	// daemonLogName is the file, in the lbrynet directory, the output of the daemon is appended to
+	daemonLogName = "ytsync_lbrynet.log"
+	// daemonConfigName is the file, in the lbrynet directory, the settings of the daemon are written to
+	daemonConfigName = "ytsync_daemon_settings.yml"
+	tailLines        = 20
+)
+
+// supervisor runs a lbrynet daemon as a child process and restarts it if it crashes
+type supervisor struct {
+	instance Instance
+	mux      sync.Mutex
+	cmd      *exec.Cmd
+	// exited is closed when the current process exits
+	exited   chan struct{}
+	stopping bool
+	restarts int
+	// failed is set once the daemon crashed too many times to be restarted
+	failed  bool
+	logFile *os.File
+	tail    *tailWriter
+}
+
+var (
+	supervisors    = map[string]*supervisor{}
+	supervisorsMux sync.Mutex
+)
+
+func getSupervisor(i Instance) *supervisor {
+	supervisorsMux.Lock()
+	defer supervisorsMux.Unlock()
+	s, ok := supervisors[i.name()]
+	if !ok {
+		s = &supervisor{instance: i}
+		supervisors[i.name()] = s
+	}
+	return s
+}
+
+// daemonSettings returns the settings of the daemon: the lbrynet config of the environment, if any, with the
+// directories and the ports of the instance
+func (i Instance) daemonSettings() (map[string]interface{}, error) {
+	settings := make(map[string]interface{})
+	if environment.LbrynetConfig != "" {
+		content, err := ioutil.ReadFile(environment.LbrynetConfig)
+		if err != nil {
+			return nil, errors.Err(err)
+		}
+		err = yaml.Unmarshal(content, &settings)
+		if err != nil {
+			return nil, errors.Prefix("could not parse the lbrynet config "+environment.LbrynetConfig, err)
+		}
+	}
+	settings["data_dir"] = i.GetLBRYNetDir()
+	settings["wallet_dir"] = i.GetWalletsDir()
+	settings["download_dir"] = filepath.Join(i.GetLBRYNetDir(), "downloads")
+	if i.Address != "" {
+		u, err := url.Parse(i.Address)
+		if err != nil {
+			return nil, errors.Err(err)
+		}
+		settings["api"] = u.Host
+	}
+	if i.TCPPort != 0 {
+		settings["tcp_port"] = i.TCPPort
+	}
+	if i.UDPPort != 0 {
+		settings["udp_port"] = i.UDPPort
+	}
+	return settings, nil
+}
+
+func (s *supervisor) writeSettings() (string, error) {
+	settings, err := s.instance.daemonSettings()
+	if err != nil {
+		return "", err
+	}
+	content, err := yaml.Marshal(settings)
+	if err != nil {
+		return "", errors.Err(err)
+	}
+	err = os.MkdirAll(s.instance.GetLBRYNetDir(), 0755)
+	if err != nil {
+		return "", errors.Err(err)
+	}
+	path := filepath.Join(s.instance.GetLBRYNetDir(), daemonConfigName)
+	err = ioutil.WriteFile(path, content, 0644)
+	if err != nil {
+		return "", errors.Err(err)
+	}
+	return path, nil
+}
+
+// start spawns the daemon unless it's already running
+func (s *supervisor) start() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.cmd != nil {
+		return nil
+	}
+	s.stopping = false
+	s.restarts = 0
+	s.failed = false
+	configPath, err := s.writeSettings()
+	if err != nil {
+		return err
+	}
+	s.logFile, err = os.OpenFile(filepath.Join(s.instance.GetLBRYNetDir(), daemonLogName), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.Err(err)
+	}
+	s.tail = newTailWriter(tailLines)
+	err = s.spawn(configPath)
+	if err != nil {
+		_ = s.logFile.Close()
+		return err
+	}
+	return nil
+}
+
+// spawn starts the process and watches it. s.mux must be held
+func (s *supervisor) spawn(configPath string) error {
+	binary := environment.LbrynetBinary
+	if binary == "" {
+		binary = "lbrynet"
+	}
+	cmd := exec.Command(binary, "start", "--config", configPath)
+	setParentDeathSignal(cmd)
+	output := &teeWriter{s.logFile, s.tail}
+	cmd.Stdout = output
+	cmd.Stderr = output
+	err := cmd.Start()
+	if err != nil {
+		return errors.Prefix("could not start "+binary, err)
+	}
+	log.Infof("daemon %s started with pid %d", s.instance, cmd.Process.Pid)
+	s.cmd = cmd
+	exited := make(chan struct{})
+	s.exited = exited
+	go s.watch(cmd, exited, configPath)
+	return nil
+}
+
+// watch waits for the process to exit and restarts it if it wasn't asked to stop
+func (s *supervisor) watch(cmd *exec.Cmd, exited chan struct{}, configPath string) {
+	err := cmd.Wait()
+	close(exited)
+
+	s.mux.Lock()
+	if s.cmd != cmd {
+		s.mux.Unlock()
+		return
+	}
+	s.cmd = nil
+	if s.stopping {
+		_ = s.logFile.Close()
+		s.mux.Unlock()
+		return
+	}
+	status := "exited"
+	if err != nil {
+		status = err.Error()
+	}
+	if s.restarts >= maxDaemonRestarts {
+		s.failed = true
+		_ = s.logFile.Close()
+		s.mux.Unlock()
+		SendErrorToSlack("daemon %s crashed (%s) and was restarted too many times, giving up. Last output:\n%s", s.instance, status, s.tail.String())
+		return
+	}
+	s.restarts++
+	restarts := s.restarts
+	s.mux.Unlock()
+
+	SendErrorToSlack("daemon %s crashed (%s), restarting it (%d/%d). Last output:\n%s", s.instance, status, restarts, maxDaemonRestarts, s.tail.String())
+	time.Sleep(time.Duration(restarts) * 5 * time.Second)
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.cmd != nil {
+		// started again in the meantime
+		return
+	}
+	if s.stopping {
+		_ = s.logFile.Close()
+		return
+	}
+	err = s.spawn(configPath)
+	if err != nil {
+		s.failed = true
+		SendErrorToSlack("could not restart daemon %s: %s", s.instance, err.Error())
+		_ = s.logFile.Close()
+	}
+}
+
+// stop asks the daemon to exit and kills it if it's still running after the timeout
+func (s *supervisor) stop() error {
+	s.mux.Lock()
+	s.stopping = true
+	cmd := s.cmd
+	exited := s.exited
+	s.mux.Unlock()
+	if cmd == nil {
+		return nil
+	}
+
+	err := cmd.Process.Signal(syscall.SIGTERM)
+	if err != nil {
+		return errors.Err(err)
+	}
+	select {
+	case <-exited:
+		return nil
+	case <-time.After(daemonStopTimeout):
+		log.Errorf("daemon %s didn't exit within %s, killing it", s.instance, daemonStopTimeout)
+		err = cmd.Process.Kill()
+		if err != nil {
+			return errors.Err(err)
+		}
+		<-exited
+		return nil
+	}
+}
+
+func (s *supervisor) isRunning() bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.cmd != nil
+}
+
+func (s *supervisor) hasFailed() bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.failed
+}
+
+type teeWriter struct {
+	file *os.File
+	tail *tailWriter
+}
+
+func (t *teeWriter) Write(p []byte) (int, error) {
+	_, _ = t.tail.Write(p)
+	return t.file.Write(p)
+}
+
+// tailWriter keeps the last lines written to it
+type tailWriter struct {
+	mux   sync.Mutex
+	max   int
+	lines []string
+	// partial is the last line, until its end is written
+	partial string
+}
+
+func newTailWriter(max int) *tailWriter {
+	return &tailWriter{max: max}
+}
+
+func (t *tailWriter) Write(p []byte) (int, error) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	parts := strings.Split(t.partial+string(p), "\n")
+	t.partial = parts[len(parts)-1]
+	t.lines = append(t.lines, parts[:len(parts)-1]...)
+	if len(t.lines) > t.max {
+		t.lines = t.lines[len(t.lines)-t.max:]
+	}
+	return len(p), nil
+}
+
+func (t *tailWriter) String() string {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	lines := t.lines
+	if t.partial != "" {
+		lines = append(lines[:len(lines):len(lines)], t.partial)
+	}
+	if len(lines) > t.max {
+		lines = lines[len(lines)-t.max:]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,11 @@
+package util
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setParentDeathSignal makes sure the daemon doesn't outlive ytsync
+func setParentDeathSignal(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Pdeathsig: syscall.SIGTERM}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package util
+
+import "os/exec"
+
+// setParentDeathSignal does nothing, only linux can signal a process when its parent dies
+func setParentDeathSignal(cmd *exec.Cmd) {}
@@ -0,0 +1,46 @@
+package util
+
+import (
+	"testing"
+)
+
+func TestTailWriter(t *testing.T) {
+	tail := newTailWriter(2)
+	_, _ = tail.Write([]byte("first\nsecond\nthi"))
+	_, _ = tail.Write([]byte("rd\nfourth"))
+	if tail.String() != "third\nfourth" {
+		t.Errorf("unexpected tail: %q", tail.String())
+	}
+	_, _ = tail.Write([]byte("\n"))
+	if tail.String() != "third\nfourth" {
+		t.Errorf("unexpected tail: %q", tail.String())
+	}
+}
+
+func TestDaemonSettings(t *testing.T) {
+	i := Instance{
+		Name:       "lbrynet-1",
+		Address:    "http://localhost:5280/",
+		LbrynetDir: "/srv/1/lbrynet",
+		WalletsDir: "/srv/1/lbryum",
+		TCPPort:    3334,
+		UDPPort:    4445,
+	}
+	settings, err := i.daemonSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"data_dir":     "/srv/1/lbrynet",
+		"wallet_dir":   "/srv/1/lbryum",
+		"download_dir": "/srv/1/lbrynet/downloads",
+		"api":          "localhost:5280",
+		"tcp_port":     3334,
+		"udp_port":     4445,
+	}
+	for key, value := range expected {
+		if settings[key] != value {
+			t.Errorf("expected %s to be %v, got %v", key, value, settings[key])
+		}
+	}
+}
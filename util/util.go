@@ -2,11 +2,7 @@ package util
 
 import (
 	"context"
-	"os"
-	"os/exec"
 	"os/user"
-	"path/filepath"
-	"strconv"
 
 	"github.com/lbryio/lbry.go/v2/extras/errors"
 	"github.com/lbryio/lbry.go/v2/lbrycrd"
@@ -14,12 +10,37 @@ import (
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/client"
-	"github.com/mitchellh/go-ps"
 	log "github.com/sirupsen/logrus"
 )
 
+// Environment describes the local lbrynet setup the helpers of this package work with
+type Environment struct {
+	// BlobsDir, LbrynetDir and WalletsDir default to the directories used by lbrynet in $HOME
+	BlobsDir   string `yaml:"blobs_dir"`
+	LbrynetDir string `yaml:"lbrynet_dir"`
+	WalletsDir string `yaml:"wallets_dir"`
+	UseDocker  bool   `yaml:"use_docker"`
+	// DaemonMode is how the daemons are run: DaemonModeSystemd, DaemonModeDocker or DaemonModeProcess. When it's empty
+	// UseDocker picks between systemd and docker
+	DaemonMode string `yaml:"daemon_mode"`
+	// LbrynetBinary is the lbrynet executable run in the process mode, it's looked up in the PATH by default
+	LbrynetBinary string `yaml:"lbrynet_binary"`
+	// LbrynetConfig is a lbrynet config file the daemons run in the process mode start from
+	LbrynetConfig  string `yaml:"lbrynet_config"`
+	Regtest        bool   `yaml:"regtest"`
+	CleanOnStartup bool   `yaml:"clean_on_startup"`
+	ReflectBlobs   bool   `yaml:"reflect_blobs"`
+}
+
+var environment = Environment{ReflectBlobs: true}
+
+// SetEnvironment replaces the settings used by the helpers of this package
+func SetEnvironment(e Environment) {
+	environment = e
+}
+
 func GetBlobsDir() string {
-	blobsDir := os.Getenv("BLOBS_DIRECTORY")
+	blobsDir := environment.BlobsDir
 	if blobsDir == "" {
 		usr, err := user.Current()
 		if err != nil {
@@ -33,11 +54,11 @@ func GetBlobsDir() string {
 }
 
 func IsBlobReflectionOff() bool {
-	return os.Getenv("REFLECT_BLOBS") == "false"
+	return !environment.ReflectBlobs
 }
 
 func GetLBRYNetDir() string {
-	lbrynetDir := os.Getenv("LBRYNET_DIR")
+	lbrynetDir := environment.LbrynetDir
 	if lbrynetDir == "" {
 		usr, err := user.Current()
 		if err != nil {
@@ -52,10 +73,6 @@ func GetLBRYNetDir() string {
 const ALL = true
 const ONLINE = false
 
-func GetLBRYNetContainer(all bool) (*types.Container, error) {
-	return getDockerContainer("lbrynet", all)
-}
-
 func getDockerContainer(name string, all bool) (*types.Container, error) {
 	cli, err := client.NewEnvClient()
 	if err != nil {
@@ -77,20 +94,31 @@ func getDockerContainer(name string, all bool) (*types.Container, error) {
 	return &containers[0], nil
 }
 
-func IsUsingDocker() bool {
-	useDocker, err := strconv.ParseBool(os.Getenv("LBRYNET_USE_DOCKER"))
-	if err != nil {
-		return false
+const (
+	DaemonModeSystemd = "systemd" // the daemons are systemd services, started and stopped with sudo
+	DaemonModeDocker  = "docker"  // the daemons are docker containers
+	DaemonModeProcess = "process" // the daemons are child processes of ytsync
+)
+
+var DaemonModes = []string{DaemonModeSystemd, DaemonModeDocker, DaemonModeProcess}
+
+// GetDaemonMode returns how the daemons are run
+func GetDaemonMode() string {
+	if environment.DaemonMode != "" {
+		return environment.DaemonMode
+	}
+	if environment.UseDocker {
+		return DaemonModeDocker
 	}
-	return useDocker
+	return DaemonModeSystemd
+}
+
+func IsUsingDocker() bool {
+	return GetDaemonMode() == DaemonModeDocker
 }
 
 func IsRegTest() bool {
-	usesRegtest, err := strconv.ParseBool(os.Getenv("REGTEST"))
-	if err != nil {
-		return false
-	}
-	return usesRegtest
+	return environment.Regtest
 }
 
 func GetLbrycrdClient(lbrycrdString string) (*lbrycrd.Client, error) {
@@ -112,194 +140,5 @@ func GetLbrycrdClient(lbrycrdString string) (*lbrycrd.Client, error) {
 }
 
 func ShouldCleanOnStartup() bool {
-	shouldClean, err := strconv.ParseBool(os.Getenv("CLEAN_ON_STARTUP"))
-	if err != nil {
-		return false
-	}
-	return shouldClean
-}
-
-func IsLbrynetRunning() (bool, error) {
-	if IsUsingDocker() {
-		container, err := GetLBRYNetContainer(ONLINE)
-		if err != nil {
-			return false, err
-		}
-		return container != nil, nil
-	}
-
-	processes, err := ps.Processes()
-	if err != nil {
-		return true, errors.Err(err)
-	}
-	var daemonProcessId = -1
-	for _, p := range processes {
-		if p.Executable() == "lbrynet" {
-			daemonProcessId = p.Pid()
-			break
-		}
-	}
-
-	running := daemonProcessId != -1
-	return running, nil
-}
-
-func CleanForStartup() error {
-	if !IsRegTest() {
-		return errors.Err("never cleanup wallet outside of regtest and with caution. this should only be done in local testing and requires regtest to be on")
-	}
-
-	running, err := IsLbrynetRunning()
-	if err != nil {
-		return err
-	}
-	if running {
-		err := StopDaemon()
-		if err != nil {
-			return err
-		}
-	}
-
-	err = CleanupLbrynet()
-	if err != nil {
-		return errors.Err(err)
-	}
-
-	lbrycrd, err := GetLbrycrdClient(os.Getenv("LBRYCRD_STRING"))
-	if err != nil {
-		return errors.Prefix("error getting lbrycrd client: ", err)
-	}
-	height, err := lbrycrd.GetBlockCount()
-	if err != nil {
-		return errors.Err(err)
-	}
-	const minBlocksForUTXO = 200
-	if height < minBlocksForUTXO {
-		//Start reg test with some credits
-		txs, err := lbrycrd.Generate(uint32(minBlocksForUTXO) - uint32(height))
-		if err != nil {
-			return errors.Err(err)
-		}
-		log.Debugf("REGTEST: Generated %d transactions to get some LBC!", len(txs))
-	}
-
-	defaultWalletDir := GetDefaultWalletPath()
-	_, err = os.Stat(defaultWalletDir)
-	if os.IsNotExist(err) {
-		return nil
-	}
-	return errors.Err(os.Remove(defaultWalletDir))
-}
-
-func CleanupLbrynet() error {
-	//make sure lbrynet is off
-	running, err := IsLbrynetRunning()
-	if err != nil {
-		return err
-	}
-	if running {
-		return errors.Prefix("cannot cleanup lbrynet as the daemon is running", err)
-	}
-	lbrynetDir := GetLBRYNetDir()
-	files, err := filepath.Glob(lbrynetDir + "lbrynet.sqlite*")
-	if err != nil {
-		return errors.Err(err)
-	}
-	for _, f := range files {
-		err = os.Remove(f)
-		if err != nil {
-			return errors.Err(err)
-		}
-	}
-	blobsDir := GetBlobsDir()
-	err = os.RemoveAll(blobsDir)
-	if err != nil {
-		return errors.Err(err)
-	}
-	err = os.Mkdir(blobsDir, 0777)
-	if err != nil {
-		return errors.Err(err)
-	}
-	return nil
-}
-
-func StartDaemon() error {
-	if IsUsingDocker() {
-		return startDaemonViaDocker()
-	}
-	return startDaemonViaSystemd()
-}
-
-func StopDaemon() error {
-	if IsUsingDocker() {
-		return stopDaemonViaDocker()
-	}
-	return stopDaemonViaSystemd()
-}
-
-func startDaemonViaDocker() error {
-	container, err := GetLBRYNetContainer(true)
-	if err != nil {
-		return err
-	}
-
-	cli, err := client.NewEnvClient()
-	if err != nil {
-		panic(err)
-	}
-
-	err = cli.ContainerStart(context.Background(), container.ID, types.ContainerStartOptions{})
-	if err != nil {
-		return errors.Err(err)
-	}
-
-	return nil
-}
-
-func stopDaemonViaDocker() error {
-	container, err := GetLBRYNetContainer(ONLINE)
-	if err != nil {
-		return err
-	}
-
-	cli, err := client.NewEnvClient()
-	if err != nil {
-		panic(err)
-	}
-
-	err = cli.ContainerStop(context.Background(), container.ID, nil)
-	if err != nil {
-		return errors.Err(err)
-	}
-
-	return nil
-}
-
-func startDaemonViaSystemd() error {
-	err := exec.Command("/usr/bin/sudo", "/bin/systemctl", "start", "lbrynet.service").Run()
-	if err != nil {
-		return errors.Err(err)
-	}
-	return nil
-}
-
-func stopDaemonViaSystemd() error {
-	err := exec.Command("/usr/bin/sudo", "/bin/systemctl", "stop", "lbrynet.service").Run()
-	if err != nil {
-		return errors.Err(err)
-	}
-	return nil
-}
-
-func GetDefaultWalletPath() string {
-	defaultWalletDir := os.Getenv("HOME") + "/.lbryum/wallets/default_wallet"
-	if IsRegTest() {
-		defaultWalletDir = os.Getenv("HOME") + "/.lbryum_regtest/wallets/default_wallet"
-	}
-
-	walletPath := os.Getenv("LBRYNET_WALLETS_DIR")
-	if walletPath != "" {
-		defaultWalletDir = walletPath + "/wallets/default_wallet"
-	}
-	return defaultWalletDir
+	return environment.CleanOnStartup
 }
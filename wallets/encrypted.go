@@ -0,0 +1,88 @@
+package wallets
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// encryptedHeader starts the encrypted wallets, it's followed by the nonce and the sealed wallet
+var encryptedHeader = []byte("ytsync-wallet-aes-gcm-v1\n")
+
+// EncryptedStore encrypts the wallets with AES-256-GCM before they're stored, so the private keys they hold aren't
+// readable by whoever has access to the storage. The key of a wallet is authenticated along with it, a wallet can't
+// be passed off as the one of another channel
+type EncryptedStore struct {
+	store WalletStore
+	aead  cipher.AEAD
+}
+
+// ParseKey decodes a hex encoded 32 bytes key
+func ParseKey(hexKey string) ([]byte, error) {
+	key, err := hex.DecodeString(hexKey)
+	if err != nil || len(key) != 32 {
+		return nil, errors.Err("the wallet encryption key must be 32 bytes, hex encoded")
+	}
+	return key, nil
+}
+
+// NewEncryptedStore wraps store with the encryption. key is 32 bytes long
+func NewEncryptedStore(store WalletStore, key []byte) (*EncryptedStore, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	return &EncryptedStore{store: store, aead: aead}, nil
+}
+
+// IsEncrypted returns true if the wallet was stored by an EncryptedStore
+func IsEncrypted(wallet []byte) bool {
+	return bytes.HasPrefix(wallet, encryptedHeader)
+}
+
+// Get decrypts the wallet. The wallets stored before the encryption was enabled are returned as they are, they're
+// encrypted the next time they're stored
+func (e *EncryptedStore) Get(key string) ([]byte, error) {
+	data, err := e.store.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	if !IsEncrypted(data) {
+		log.Warnf("the wallet %s is not encrypted, it will be once it's uploaded again", key)
+		return data, nil
+	}
+	data = data[len(encryptedHeader):]
+	if len(data) < e.aead.NonceSize() {
+		return nil, errors.Err("the wallet %s is truncated", key)
+	}
+	nonce, sealed := data[:e.aead.NonceSize()], data[e.aead.NonceSize():]
+	wallet, err := e.aead.Open(nil, nonce, sealed, []byte(key))
+	if err != nil {
+		return nil, errors.Err("could not decrypt the wallet %s, the key is wrong or the wallet was tampered with", key)
+	}
+	return wallet, nil
+}
+
+func (e *EncryptedStore) Put(key string, wallet []byte) error {
+	nonce := make([]byte, e.aead.NonceSize())
+	_, err := io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return errors.Err(err)
+	}
+	data := make([]byte, 0, len(encryptedHeader)+len(nonce)+len(wallet)+e.aead.Overhead())
+	data = append(data, encryptedHeader...)
+	data = append(data, nonce...)
+	data = e.aead.Seal(data, nonce, wallet, []byte(key))
+	return e.store.Put(key, data)
+}
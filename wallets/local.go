@@ -0,0 +1,65 @@
+package wallets
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+)
+
+// LocalStore keeps the wallets in a directory, for the setups where a single server syncs the channels
+type LocalStore struct {
+	dir string
+}
+
+// NewLocalStore creates a store in dir, which is created if needed
+func NewLocalStore(dir string) (*LocalStore, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	return &LocalStore{dir: dir}, nil
+}
+
+// path maps the key to a file of the directory, the keys can't point out of it
+func (l *LocalStore) path(key string) string {
+	return filepath.Join(l.dir, filepath.FromSlash(path.Clean("/"+key)))
+}
+
+func (l *LocalStore) Get(key string) ([]byte, error) {
+	wallet, err := ioutil.ReadFile(l.path(key))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	return wallet, nil
+}
+
+// Put writes the wallet next to the one it replaces first, so a crash can't leave half a wallet behind
+func (l *LocalStore) Put(key string, wallet []byte) error {
+	p := l.path(key)
+	err := os.MkdirAll(filepath.Dir(p), 0700)
+	if err != nil {
+		return errors.Err(err)
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(p), filepath.Base(p)+".*.tmp")
+	if err != nil {
+		return errors.Err(err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(wallet)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.Err(err)
+	}
+	return errors.Err(os.Rename(tmp.Name(), p))
+}
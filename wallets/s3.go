@@ -0,0 +1,61 @@
+package wallets
+
+import (
+	"bytes"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// S3Store keeps the wallets in an S3 bucket
+type S3Store struct {
+	session *session.Session
+	bucket  string
+}
+
+// NewS3Store creates a store for the bucket. endpoint can be set to use an S3 compatible storage like MinIO, the
+// buckets are then addressed by path
+func NewS3Store(config aws.Config, endpoint string, bucket string) (*S3Store, error) {
+	if endpoint != "" {
+		config.Endpoint = aws.String(endpoint)
+		config.S3ForcePathStyle = aws.Bool(true)
+	}
+	s3Session, err := session.NewSession(&config)
+	if err != nil {
+		return nil, errors.Prefix("error starting session", err)
+	}
+	return &S3Store{session: s3Session, bucket: bucket}, nil
+}
+
+func (s *S3Store) Get(key string) ([]byte, error) {
+	buf := aws.NewWriteAtBuffer(nil)
+	bytesWritten, err := s3manager.NewDownloader(s.session).Download(buf, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		// see http://docs.aws.amazon.com/AmazonS3/latest/API/ErrorResponses.html for the codes
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, ErrNotFound
+		}
+		return nil, errors.Err(err)
+	}
+	if bytesWritten == 0 {
+		return nil, errors.Err("zero bytes written")
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *S3Store) Put(key string, wallet []byte) error {
+	_, err := s3manager.NewUploader(s.session).Upload(&s3manager.UploadInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(wallet),
+	})
+	return errors.Err(err)
+}
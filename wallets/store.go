@@ -0,0 +1,24 @@
+package wallets
+
+import (
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+)
+
+const (
+	BackendS3    = "s3"
+	BackendLocal = "local"
+)
+
+var Backends = []string{BackendS3, BackendLocal}
+
+// ErrNotFound is returned when there's no wallet under a key, which is the case of the channels never synced before
+var ErrNotFound = errors.Base("wallet not found")
+
+// WalletStore keeps the wallets of the channels between syncs. The keys are the paths the wallets always had in the
+// bucket, like /wallets/<youtube channel ID>
+type WalletStore interface {
+	// Get returns the wallet stored under key, ErrNotFound if there's none
+	Get(key string) ([]byte, error)
+	// Put stores the wallet under key, replacing the one that was there
+	Put(key string, wallet []byte) error
+}
@@ -0,0 +1,104 @@
+package wallets
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+)
+
+func testStore(t *testing.T) (*LocalStore, func()) {
+	dir, err := ioutil.TempDir("", "ytsync-wallets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewLocalStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, func() { os.RemoveAll(dir) }
+}
+
+func TestLocalStore(t *testing.T) {
+	store, cleanup := testStore(t)
+	defer cleanup()
+
+	_, err := store.Get("/wallets/UCaaa")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	for _, wallet := range []string{"first", "second"} {
+		err = store.Put("/wallets/UCaaa", []byte(wallet))
+		if err != nil {
+			t.Fatal(err)
+		}
+		stored, err := store.Get("/wallets/UCaaa")
+		if err != nil || string(stored) != wallet {
+			t.Errorf("expected %q, got %q (%v)", wallet, stored, err)
+		}
+	}
+	if p := store.path("/../../etc/passwd"); p != filepath.Join(store.dir, "etc", "passwd") {
+		t.Errorf("keys must stay in the directory, got %s", p)
+	}
+}
+
+func TestEncryptedStore(t *testing.T) {
+	store, cleanup := testStore(t)
+	defer cleanup()
+	key, err := ParseKey("000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f")
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := NewEncryptedStore(store, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wallet := []byte(`{"accounts": [{"private_key": "xprv"}]}`)
+	err = encrypted.Put("/wallets/UCaaa", wallet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := store.Get("/wallets/UCaaa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(raw) || bytes.Contains(raw, []byte("xprv")) {
+		t.Errorf("the wallet is stored in plain text: %q", raw)
+	}
+	decrypted, err := encrypted.Get("/wallets/UCaaa")
+	if err != nil || !bytes.Equal(decrypted, wallet) {
+		t.Errorf("expected %q, got %q (%v)", wallet, decrypted, err)
+	}
+
+	// a wallet can't be decrypted under another key, nor with another encryption key
+	err = store.Put("/wallets/UCbbb", raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := encrypted.Get("/wallets/UCbbb"); err == nil {
+		t.Error("a wallet moved to another key must not be decrypted")
+	}
+	otherKey, _ := ParseKey("ff0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f")
+	other, _ := NewEncryptedStore(store, otherKey)
+	if _, err := other.Get("/wallets/UCaaa"); err == nil {
+		t.Error("a wallet must not be decrypted with the wrong key")
+	}
+
+	// the wallets stored before the encryption was enabled are still readable
+	err = store.Put("/wallets/UCccc", wallet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := encrypted.Get("/wallets/UCccc")
+	if err != nil || !bytes.Equal(plain, wallet) {
+		t.Errorf("expected the plain wallet, got %q (%v)", plain, err)
+	}
+
+	if _, err := ParseKey("abcd"); err == nil {
+		t.Error("short keys must be rejected")
+	}
+}